@@ -2,6 +2,7 @@ package config
 
 import (
 	"flag"
+	"time"
 
 	"github.com/caarlos0/env"
 )
@@ -9,13 +10,74 @@ import (
 type Config struct {
 	ServerAddress string `env:"SERVER_ADDRESS" envDefault:"127.0.0.1:8080"`
 	DSN           string `env:"DATABASE_DSN" envDefault:""`
+
+	// public base URL of short links returned by HTTP and gRPC, its host is never shortened
+	BaseURL string `env:"BASE_URL"`
+
+	// gRPC API is not started if empty
+	GRPCAddress string `env:"GRPC_ADDRESS"`
+
+	// deleted URLs can be restored during DeleteRetention, after that they are purged
+	DeleteRetention time.Duration `env:"DELETE_RETENTION" envDefault:"720h"`
+	PurgeInterval   time.Duration `env:"PURGE_INTERVAL" envDefault:"1h"`
+
+	// destination URL checks, blocklist file is reloaded on SIGHUP,
+	// host names are resolved to find private addresses, without resolving only IP hosts are checked
+	AllowedSchemes    []string `env:"ALLOWED_SCHEMES" envDefault:"http,https" envSeparator:","`
+	BlockPrivateHosts bool     `env:"BLOCK_PRIVATE_HOSTS" envDefault:"true"`
+	ResolveHosts      bool     `env:"RESOLVE_HOSTS" envDefault:"true"`
+	BlocklistFile     string   `env:"BLOCKLIST_FILE" envDefault:""`
+	SelfHosts         []string `env:"SELF_HOSTS" envSeparator:","`
+
+	// remove utm_* and click ID parameters from canonical URL used to find duplicates
+	StripTrackingParams bool `env:"STRIP_TRACKING_PARAMS" envDefault:"false"`
+
+	// scope of long URL uniqueness: global, user or none
+	DedupMode string `env:"DEDUP_MODE" envDefault:"user"`
+
+	// unknown short URLs are redirected here instead of 404 if set
+	NotFoundRedirect string `env:"NOT_FOUND_REDIRECT" envDefault:""`
+
+	// lines of bulk import inserted with one statement
+	ImportChunkSize int `env:"IMPORT_CHUNK_SIZE" envDefault:"1000"`
+
+	// max size of bulk import request body, 0 means no limit
+	ImportMaxBytes int64 `env:"IMPORT_MAX_BYTES" envDefault:"33554432"`
+
+	// max lines of bulk import request body, 0 means no limit
+	ImportMaxLines int `env:"IMPORT_MAX_LINES" envDefault:"100000"`
+
+	// max items in one request to /api/shorten/batch, 0 means no limit
+	BatchMaxSize int `env:"BATCH_MAX_SIZE" envDefault:"1000"`
+
+	// CIDR of clients allowed to /api/internal, access is denied to everyone if empty
+	TrustedSubnet string `env:"TRUSTED_SUBNET" envDefault:""`
+
+	// CIDRs of reverse proxies whose X-Real-IP is taken as client address, RemoteAddr is used otherwise
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:","`
+
+	// bearer token of /api/admin, admin API is disabled if empty
+	AdminToken string `env:"ADMIN_TOKEN" envDefault:""`
+
+	// rendered QR codes kept in memory, 0 disables cache
+	QRCacheSize int `env:"QR_CACHE_SIZE" envDefault:"1000"`
+
+	// wrong passwords allowed from one IP to one link during window
+	PasswordAttempts int           `env:"PASSWORD_ATTEMPTS" envDefault:"5"`
+	PasswordWindow   time.Duration `env:"PASSWORD_WINDOW" envDefault:"15m"`
+
+	// MaxMind country database for country rules of links, reloaded on SIGHUP, country is unknown if empty
+	GeoIPFile string `env:"GEOIP_FILE" envDefault:""`
 }
 
 func GetConfig() (*Config, error) {
 
 	cfg := &Config{}
 	flag.StringVar(&cfg.ServerAddress, "f", cfg.ServerAddress, "SERVER_ADDRESS")
+	flag.StringVar(&cfg.BaseURL, "b", "http://127.0.0.1:8080", "BASE_URL")
 	flag.StringVar(&cfg.DSN, "d", cfg.DSN, "DATABASE_DSN")
+	flag.StringVar(&cfg.GRPCAddress, "g", "127.0.0.1:3200", "GRPC_ADDRESS")
+	flag.StringVar(&cfg.TrustedSubnet, "t", cfg.TrustedSubnet, "TRUSTED_SUBNET")
 
 	flag.Parse()
 	err := env.Parse(cfg)
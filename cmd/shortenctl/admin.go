@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/repository"
+	"github.com/RomanIkonnikov93/URLshortner/internal/service"
+	"github.com/RomanIkonnikov93/URLshortner/internal/validation"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+)
+
+// openRepository parse shortener flags and environment and connect to database,
+// tables are created and migrated by repository constructors
+func openRepository(args []string) (*config.Config, *repository.Pool, error) {
+
+	os.Args = append(os.Args[:1], args...)
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rep, err := repository.NewReps(*cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, rep, nil
+}
+
+func runStats(args []string) error {
+
+	cfg, rep, err := openRepository(args)
+	if err != nil {
+		return err
+	}
+	v, err := validation.NewValidator(*cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), model.TimeOut)
+	defer cancel()
+
+	st, err := service.NewShortener(*rep, v, nil, *cfg, *logging.GetLogger()).Stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&st)
+}
+
+// runPurge remove URLs and delete jobs older than DELETE_RETENTION now instead of waiting for purge interval
+func runPurge(args []string) error {
+
+	cfg, rep, err := openRepository(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), model.TimeOut)
+	defer cancel()
+
+	n, err := rep.Storage.Purge(ctx, cfg.DeleteRetention)
+	if err != nil {
+		return err
+	}
+	j, err := rep.Jobs.Purge(ctx, cfg.DeleteRetention)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("purged %d deleted urls and %d delete jobs", n, j)
+	return nil
+}
+
+func runMigrate(args []string) error {
+
+	if _, _, err := openRepository(args); err != nil {
+		return err
+	}
+
+	log.Print("database schema is up to date")
+	return nil
+}
+
+// runRebuildCache drop QR codes cached by running server at SHORTENER_URL through admin API with ADMIN_TOKEN,
+// they are rendered again on next request, links themselves are always read from database
+func runRebuildCache(args []string) error {
+
+	os.Args = append(os.Args[:1], args...)
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.AdminToken == "" {
+		return errors.New("ADMIN_TOKEN is not set")
+	}
+
+	c := newClient()
+	c.admin = cfg.AdminToken
+	resp, err := c.do(http.MethodDelete, "/api/admin/cache", nil)
+	if err != nil {
+		return err
+	}
+
+	var res model.ClearCacheResponse
+	if err := decode(resp, &res); err != nil {
+		return err
+	}
+
+	log.Printf("dropped %d cached qr codes", res.QRCodes)
+	return nil
+}
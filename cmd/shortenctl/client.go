@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+// client of shortener HTTP API authenticated by user token cookie
+type client struct {
+	base  string
+	token string
+	admin string
+	http  *http.Client
+}
+
+func newClient() *client {
+
+	base := os.Getenv("SHORTENER_URL")
+	if base == "" {
+		base = "http://127.0.0.1:8080"
+	}
+
+	return &client{
+		base:  strings.TrimRight(base, "/"),
+		token: os.Getenv("SHORTENER_TOKEN"),
+		http:  &http.Client{Timeout: time.Minute},
+	}
+}
+
+// do send request, return response with 2xx or 409 status, other statuses are returned as error with problem detail
+func (c *client) do(method, path string, body []byte) (*http.Response, error) {
+
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, c.base+path, rd)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.AddCookie(&http.Cookie{Name: "UserTokenID", Value: c.token})
+	}
+	if c.admin != "" {
+		req.Header.Set("Authorization", "Bearer "+c.admin)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "UserTokenID" && cookie.Value != c.token {
+			c.token = cookie.Value
+			log.Printf("new user token %s, set SHORTENER_TOKEN to keep using it", c.token)
+		}
+	}
+
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusConflict {
+		defer resp.Body.Close()
+		p := struct {
+			Detail string `json:"detail"`
+		}{}
+		b, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(b, &p) == nil && p.Detail != "" {
+			return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, p.Detail)
+		}
+		return nil, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+
+	return resp, nil
+}
+
+// decode read JSON response body into v
+func decode(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func runShorten(args []string) error {
+
+	fs := flag.NewFlagSet("shorten", flag.ExitOnError)
+	title := fs.String("title", "", "link title")
+	tags := fs.String("tags", "", "comma separated link tags")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: shortenctl shorten [-title T] [-tags a,b] URL")
+	}
+
+	req := model.URLRequest{URL: fs.Arg(0)}
+	req.Title = *title
+	if *tags != "" {
+		req.Tags = strings.Split(*tags, ",")
+	}
+	b, err := json.Marshal(&req)
+	if err != nil {
+		return err
+	}
+
+	c := newClient()
+	resp, err := c.do(http.MethodPost, "/api/shorten", b)
+	if err != nil {
+		return err
+	}
+
+	res := model.URLResponse{}
+	if err := decode(resp, &res); err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusConflict {
+		log.Printf("%s is already shortened", req.URL)
+	}
+	fmt.Println(res.Result)
+
+	return nil
+}
+
+// runBatch shorten URLs from stdin, one per line, result of every line is written in JSONL format
+func runBatch(args []string) error {
+
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	batch := model.BatchRequest{}
+	sc := bufio.NewScanner(os.Stdin)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		batch = append(batch, model.BatchItem{CorrelationID: strconv.Itoa(n), OriginalURL: line})
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(&batch)
+	if err != nil {
+		return err
+	}
+
+	c := newClient()
+	resp, err := c.do(http.MethodPost, "/api/shorten/batch", b)
+	if err != nil {
+		return err
+	}
+
+	res := []model.BatchResponse{}
+	if err := decode(resp, &res); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	for _, val := range res {
+		if err := enc.Encode(&val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nextRe extract URL of the next page from Link header
+var nextRe = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// runList write user links in JSONL format
+func runList(args []string) error {
+
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	limit := fs.Int("limit", 0, "page size")
+	sort := fs.String("sort", "", "created_at or clicks")
+	order := fs.String("order", "", "asc or desc")
+	tag := fs.String("tag", "", "only links with tag")
+	search := fs.String("q", "", "only links with long URL containing string")
+	domain := fs.String("domain", "", "only links to domain")
+	all := fs.Bool("all", false, "follow all pages")
+	_ = fs.Parse(args)
+
+	v := url.Values{}
+	if *limit > 0 {
+		v.Set("limit", strconv.Itoa(*limit))
+	}
+	for key, val := range map[string]string{"sort": *sort, "order": *order, "tag": *tag, "q": *search, "domain": *domain} {
+		if val != "" {
+			v.Set(key, val)
+		}
+	}
+	path := "/api/user/urls"
+	if len(v) > 0 {
+		path += "?" + v.Encode()
+	}
+
+	c := newClient()
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	for path != "" {
+		resp, err := c.do(http.MethodGet, path, nil)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusNoContent {
+			resp.Body.Close()
+			return nil
+		}
+
+		next := ""
+		if m := nextRe.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			next = m[1]
+		}
+
+		res := []model.URLsJSONResponse{}
+		if err := decode(resp, &res); err != nil {
+			return err
+		}
+		for _, val := range res {
+			if err := enc.Encode(&val); err != nil {
+				return err
+			}
+		}
+
+		path = ""
+		if next != "" {
+			if !*all {
+				log.Printf("next page: %s", next)
+				return nil
+			}
+			if i := strings.Index(next, "/api/"); i >= 0 {
+				path = next[i:]
+			}
+		}
+	}
+
+	return nil
+}
+
+// runDelete start delete job, with -wait poll it until finished and write result of every ID
+func runDelete(args []string) error {
+
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	wait := fs.Bool("wait", false, "wait until delete job is finished")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		return errors.New("usage: shortenctl delete [-wait] ID...")
+	}
+
+	b, err := json.Marshal(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	c := newClient()
+	resp, err := c.do(http.MethodDelete, "/api/user/urls", b)
+	if err != nil {
+		return err
+	}
+	res := model.DeleteJobResponse{}
+	if err := decode(resp, &res); err != nil {
+		return err
+	}
+	if !*wait {
+		fmt.Println(res.JobID)
+		return nil
+	}
+
+	for {
+		resp, err := c.do(http.MethodGet, "/api/user/deletions/"+res.JobID, nil)
+		if err != nil {
+			return err
+		}
+		job := model.DeleteJob{}
+		if err := decode(resp, &job); err != nil {
+			return err
+		}
+
+		switch job.Status {
+		case model.JobPending:
+			time.Sleep(500 * time.Millisecond)
+			continue
+		case model.JobFailed:
+			return fmt.Errorf("delete job %s failed", res.JobID)
+		}
+
+		for _, val := range job.Results {
+			fmt.Printf("%s\t%s\n", val.ID, val.Status)
+		}
+		return nil
+	}
+}
+
+// runExport copy export of user links to stdout
+func runExport(args []string) error {
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "jsonl", "csv, json or jsonl")
+	_ = fs.Parse(args)
+
+	c := newClient()
+	resp, err := c.do(http.MethodGet, "/api/user/urls/export?format="+url.QueryEscape(*format), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(os.Stdout, resp.Body)
+	return err
+}
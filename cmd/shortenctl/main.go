@@ -0,0 +1,62 @@
+// Command shortenctl is a client of running shortener server and admin tool working with its database.
+//
+// Client commands use SHORTENER_URL and SHORTENER_TOKEN, token is the UserTokenID cookie value,
+// new token issued by server is printed to stderr:
+//
+//	shortenctl shorten URL
+//	shortenctl batch < urls.txt
+//	shortenctl list [-limit N] [-sort created_at|clicks] [-order asc|desc] [-tag T] [-q S] [-domain D] [-all]
+//	shortenctl delete [-wait] ID...
+//	shortenctl export [-format csv|json|jsonl]
+//
+// Admin commands connect to DATABASE_DSN directly and accept flags and environment of shortener:
+//
+//	shortenctl stats
+//	shortenctl purge
+//	shortenctl migrate
+//
+// rebuild-cache drops QR codes cached by server at SHORTENER_URL through admin API with ADMIN_TOKEN:
+//
+//	shortenctl rebuild-cache
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+var commands = map[string]func(args []string) error{
+	"shorten":       runShorten,
+	"batch":         runBatch,
+	"list":          runList,
+	"delete":        runDelete,
+	"export":        runExport,
+	"stats":         runStats,
+	"purge":         runPurge,
+	"migrate":       runMigrate,
+	"rebuild-cache": runRebuildCache,
+}
+
+func main() {
+
+	log.SetFlags(0)
+	log.SetPrefix("shortenctl: ")
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+
+	if err := cmd(os.Args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: shortenctl shorten|batch|list|delete|export|stats|purge|migrate|rebuild-cache [flags] [args]")
+	os.Exit(2)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/auth"
+	"github.com/RomanIkonnikov93/URLshortner/internal/importer"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/problem"
+	"github.com/RomanIkonnikov93/URLshortner/internal/repository"
+	"github.com/RomanIkonnikov93/URLshortner/internal/service"
+	"github.com/RomanIkonnikov93/URLshortner/internal/validation"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+)
+
+// runImport import links from CSV or JSONL file directly into repository,
+// result of every line is written to stdout in JSONL format
+//
+//	shortener import -file links.csv [-format csv|jsonl] [-user ID]
+func runImport(logger *logging.Logger) {
+
+	file := flag.String("file", "", "import file, stdin if empty")
+	format := flag.String("format", "", "csv or jsonl, by file extension if empty")
+	user := flag.String("user", "", "owner of imported links, new user is created if empty")
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		logger.Fatalf("GetConfig: %s", err)
+	}
+
+	rep, err := repository.NewReps(*cfg)
+	if err != nil {
+		logger.Fatalf("NewReps: %s", err)
+	}
+
+	v, err := validation.NewValidator(*cfg)
+	if err != nil {
+		logger.Fatalf("NewValidator: %s", err)
+	}
+
+	s := service.NewShortener(*rep, v, nil, *cfg, *logger)
+
+	userID := *user
+	if userID == "" {
+		token, id, err := auth.CreateToken(model.Key, *rep)
+		if err != nil {
+			logger.Fatalf("CreateToken: %s", err)
+		}
+		userID = id
+		logger.Infof("links are imported for new user %s, token %s", id, token)
+	}
+
+	var src io.Reader = os.Stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			logger.Fatalf("Open: %s", err)
+		}
+		defer f.Close()
+		src = f
+		if *format == "" {
+			*format = strings.TrimPrefix(filepath.Ext(*file), ".")
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	counts := make(map[string]int)
+	ctx := service.ImportContext(context.Background())
+
+	err = importer.Read(src, *format, cfg.ImportChunkSize, func(lines []importer.Line) error {
+		res, err := s.Import(ctx, userID, lines)
+		if err != nil {
+			return err
+		}
+		for _, val := range res {
+			counts[val.Status]++
+			if val.Err != nil {
+				val.Error = problem.From(val.Err).Detail
+			}
+			if err := encoder.Encode(&val); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Fatalf("Import: %s", err)
+	}
+
+	logger.Infof("import finished: %d created, %d existing, %d errors",
+		counts[model.StatusCreated], counts[model.StatusExisting], counts[model.StatusError])
+}
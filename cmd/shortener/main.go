@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
 	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/geoip"
+	"github.com/RomanIkonnikov93/URLshortner/internal/grpcserver"
+	"github.com/RomanIkonnikov93/URLshortner/internal/purge"
 	"github.com/RomanIkonnikov93/URLshortner/internal/repository"
 	"github.com/RomanIkonnikov93/URLshortner/internal/server"
+	"github.com/RomanIkonnikov93/URLshortner/internal/service"
+	"github.com/RomanIkonnikov93/URLshortner/internal/validation"
 	"github.com/RomanIkonnikov93/URLshortner/logging"
 )
 
@@ -16,6 +25,13 @@ func main() {
 
 	logger := logging.GetLogger()
 
+	// subcommands
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		runImport(logger)
+		return
+	}
+
 	cfg, err := config.GetConfig()
 	if err != nil {
 		logger.Fatalf("GetConfig: %s", err)
@@ -26,7 +42,45 @@ func main() {
 		logger.Fatalf("NewReps: %s", err)
 	}
 
-	err = server.StartServer(*rep, *cfg, *logger)
+	v, err := validation.NewValidator(*cfg)
+	if err != nil {
+		logger.Fatalf("NewValidator: %s", err)
+	}
+
+	geo, err := geoip.Open(cfg.GeoIPFile)
+	if err != nil {
+		logger.Fatalf("geoip.Open: %s", err)
+	}
+
+	// reload URL blocklist and GeoIP database on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := geo.Reload(); err != nil {
+				logger.Errorf("Reload GeoIP database: %s", err)
+			}
+			if err := v.Reload(); err != nil {
+				logger.Errorf("Reload blocklist: %s", err)
+				continue
+			}
+			logger.Info("blocklist reloaded")
+		}
+	}()
+
+	go purge.Run(context.Background(), *rep, *cfg, *logger)
+
+	s := service.NewShortener(*rep, v, geo, *cfg, *logger)
+
+	if cfg.GRPCAddress != "" {
+		go func() {
+			if err := grpcserver.StartServer(s, *rep, *cfg, *logger); err != nil {
+				logger.Fatalf("grpcserver.StartServer: %s", err)
+			}
+		}()
+	}
+
+	err = server.StartServer(s, *rep, *cfg, *logger)
 	if err != nil {
 		logger.Fatalf("StartServer: %s", err)
 	}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"crypto/aes"
+	"encoding/hex"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/random"
+	"github.com/RomanIkonnikov93/URLshortner/internal/repository"
+)
+
+// userKey context key of user ID set by transport after token check
+type userKey struct{}
+
+// WithUserID return context carrying user ID
+func WithUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userKey{}, id)
+}
+
+// UserID get user ID from context, model.ErrNoUserID is returned if it is not set
+func UserID(ctx context.Context) (string, error) {
+	id, ok := ctx.Value(userKey{}).(string)
+	if !ok || id == "" {
+		return "", model.ErrNoUserID
+	}
+	return id, nil
+}
+
+func GenerateUserID() string {
+	return random.String(16)
+}
+
+func Encrypt(src, key []byte) ([]byte, error) {
+	aesblock, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	dst := make([]byte, aes.BlockSize)
+	aesblock.Encrypt(dst, src)
+	return dst, nil
+}
+
+func Decrypt(src, key []byte) ([]byte, error) {
+	aesblock, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	dst := make([]byte, aes.BlockSize)
+	aesblock.Decrypt(dst, src)
+	return dst, nil
+}
+
+// CheckToken decrypt user token of cookie or metadata, return user ID if user exists
+func CheckToken(token string, key []byte, rep repository.Pool) (string, error) {
+	src, err := hex.DecodeString(token)
+	if err != nil {
+		return "", err
+	}
+	res, err := Decrypt(src, key)
+	if err != nil {
+		return "", err
+	}
+	k := string(res)
+	exist, err := rep.Users.CheckUserID(k)
+	if err != nil {
+		return "", err
+	}
+	if exist {
+		return k, nil
+	}
+	return "", nil
+}
+
+// CreateToken add new user and return its token and ID
+func CreateToken(key []byte, rep repository.Pool) (token string, ID string, err error) {
+	ID = GenerateUserID()
+	err = rep.Users.AddUserID(ID)
+	if err != nil {
+		return "", "", err
+	}
+	c, err := Encrypt([]byte(ID), key)
+	if err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(c)
+	return
+}
@@ -0,0 +1,73 @@
+package geoip
+
+import (
+	"net"
+	"sync"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Locator find country of client IP in offline MaxMind database file
+type Locator struct {
+	file string
+
+	mu sync.RWMutex
+	db *geoip2.Reader
+}
+
+// Open read database file, nil Locator is returned if file is not set
+func Open(file string) (*Locator, error) {
+
+	if file == "" {
+		return nil, nil
+	}
+
+	db, err := geoip2.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Locator{file: file, db: db}, nil
+}
+
+// Reload open database file again, previous database is closed after replace
+func (l *Locator) Reload() error {
+
+	if l == nil {
+		return nil
+	}
+
+	db, err := geoip2.Open(l.file)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	old := l.db
+	l.db = db
+	l.mu.Unlock()
+
+	return old.Close()
+}
+
+// Country return ISO 3166-1 alpha-2 code of IP country, empty if it is unknown or database is not set
+func (l *Locator) Country(ip string) string {
+
+	if l == nil {
+		return ""
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	c, err := l.db.Country(addr)
+	if err != nil {
+		return ""
+	}
+
+	return c.Country.IsoCode
+}
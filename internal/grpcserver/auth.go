@@ -0,0 +1,80 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/auth"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/repository"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// TokenKey metadata key of user token, same value as UserTokenID cookie of HTTP API
+const TokenKey = "user_token"
+
+// pingMethod does not need user
+const pingMethod = "/shortener.Shortener/Ping"
+
+// UserInterceptor check user token from metadata, create new user and send token in header if it is missing or invalid
+func UserInterceptor(rep repository.Pool, logger logging.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+		if info.FullMethod == pingMethod {
+			return handler(ctx, req)
+		}
+
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if val := md.Get(TokenKey); len(val) > 0 {
+				userID, err := auth.CheckToken(val[0], model.Key, rep)
+				if err == nil && userID != "" {
+					banned, err := rep.Users.Banned(userID)
+					if err != nil {
+						logger.Error(err)
+					}
+					if banned {
+						return nil, status.Error(codes.PermissionDenied, model.ErrBanned.Error())
+					}
+					return handler(auth.WithUserID(ctx, userID), req)
+				}
+			}
+		}
+
+		token, userID, err := auth.CreateToken(model.Key, rep)
+		if err != nil {
+			logger.Error(err)
+			return nil, status.Error(codes.Internal, "user can not be created")
+		}
+		if err := grpc.SetHeader(ctx, metadata.Pairs(TokenKey, token)); err != nil {
+			logger.Error(err)
+		}
+
+		return handler(auth.WithUserID(ctx, userID), req)
+	}
+}
+
+// userID get user ID set by UserInterceptor
+func userID(ctx context.Context) (string, error) {
+	id, err := auth.UserID(ctx)
+	if err != nil {
+		return "", status.Error(codes.Unauthenticated, err.Error())
+	}
+	return id, nil
+}
+
+// peerIP get client address of call
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
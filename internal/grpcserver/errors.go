@@ -0,0 +1,64 @@
+package grpcserver
+
+import (
+	"errors"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/validation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// code map domain error to gRPC code, ok is false for unexpected errors
+func code(err error) (c codes.Code, ok bool) {
+
+	var verr *validation.Error
+	switch {
+	case errors.As(err, &verr), errors.Is(err, model.ErrInvalidURL), errors.Is(err, model.ErrListQuery),
+		errors.Is(err, model.ErrRedirectCode), errors.Is(err, model.ErrExpiresAt), errors.Is(err, model.ErrAlias),
+		errors.Is(err, model.ErrCursor), errors.Is(err, model.ErrTitle), errors.Is(err, model.ErrDescription),
+		errors.Is(err, model.ErrTags), errors.Is(err, model.ErrPassword), errors.Is(err, model.ErrMaxClicks),
+		errors.Is(err, model.ErrRules), errors.Is(err, model.ErrImportLine):
+		return codes.InvalidArgument, true
+	case errors.Is(err, model.ErrNotFound):
+		return codes.NotFound, true
+	case errors.Is(err, model.ErrDelFlag), errors.Is(err, model.ErrExpired), errors.Is(err, model.ErrExhausted):
+		return codes.FailedPrecondition, true
+	case errors.Is(err, model.ErrConflict), errors.Is(err, model.ErrAliasTaken):
+		return codes.AlreadyExists, true
+	case errors.Is(err, model.ErrBatchTooLarge), errors.Is(err, model.ErrTooManyAttempts):
+		return codes.ResourceExhausted, true
+	case errors.Is(err, model.ErrPasswordRequired), errors.Is(err, model.ErrNoUserID):
+		return codes.Unauthenticated, true
+	case errors.Is(err, model.ErrPasswordWrong), errors.Is(err, model.ErrBanned), errors.Is(err, model.ErrDisabled):
+		return codes.PermissionDenied, true
+	}
+	return codes.Internal, false
+}
+
+// detail message of error sent to client, unexpected errors are not described
+func detail(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, model.ErrInvalidURL) {
+		return model.ErrInvalidURL.Error()
+	}
+	if _, ok := code(err); !ok {
+		return "internal error"
+	}
+	return err.Error()
+}
+
+// error log error and map it to gRPC status
+func (s *Server) error(err error) error {
+
+	c, ok := code(err)
+	if !ok {
+		s.logger.Error(err)
+	} else {
+		s.logger.Warn(err)
+	}
+
+	return status.Error(c, detail(err))
+}
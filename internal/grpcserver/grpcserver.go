@@ -0,0 +1,220 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	pb "github.com/RomanIkonnikov93/URLshortner/internal/proto"
+	"github.com/RomanIkonnikov93/URLshortner/internal/repository"
+	"github.com/RomanIkonnikov93/URLshortner/internal/service"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implementation of gRPC Shortener service over the same service as HTTP API
+type Server struct {
+	pb.UnimplementedShortenerServer
+
+	s      *service.Shortener
+	rep    repository.Pool
+	cfg    config.Config
+	logger logging.Logger
+}
+
+// StartServer listen GRPCAddress and serve Shortener service
+func StartServer(s *service.Shortener, rep repository.Pool, cfg config.Config, logger logging.Logger) error {
+
+	lis, err := net.Listen("tcp", cfg.GRPCAddress)
+	if err != nil {
+		return err
+	}
+
+	srv := grpc.NewServer(grpc.UnaryInterceptor(UserInterceptor(rep, logger)))
+	pb.RegisterShortenerServer(srv, &Server{s: s, rep: rep, cfg: cfg, logger: logger})
+
+	logger.Info("grpc server running")
+	return srv.Serve(lis)
+}
+
+// Shorten get long URL and return short URL, existing short URL is returned for duplicate
+func (s *Server) Shorten(ctx context.Context, in *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+
+	userID, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, meta := linkOptions(in.GetOptions())
+	short, err := s.s.Shorten(ctx, userID, model.URLRequest{URL: in.GetUrl(), LinkOptions: opts, LinkMeta: meta})
+	if err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			return &pb.ShortenResponse{Result: short, Existing: true}, nil
+		}
+		return nil, s.error(err)
+	}
+
+	return &pb.ShortenResponse{Result: short}, nil
+}
+
+// ShortenBatch add batch URLs in one transaction, return short URL and status of each
+func (s *Server) ShortenBatch(ctx context.Context, in *pb.ShortenBatchRequest) (*pb.ShortenBatchResponse, error) {
+
+	userID, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := make(model.BatchRequest, 0, len(in.GetItems()))
+	for _, val := range in.GetItems() {
+		opts, meta := linkOptions(val.GetOptions())
+		batch = append(batch, model.BatchItem{CorrelationID: val.GetCorrelationId(), OriginalURL: val.GetOriginalUrl(), LinkOptions: opts, LinkMeta: meta})
+	}
+
+	res, err := s.s.ShortenBatch(ctx, userID, batch)
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	out := &pb.ShortenBatchResponse{Results: make([]*pb.BatchResult, 0, len(res))}
+	for _, val := range res {
+		out.Results = append(out.Results, &pb.BatchResult{
+			CorrelationId: val.CorrelationID,
+			ShortUrl:      val.ShortURL,
+			Status:        val.Status,
+			Error:         detail(val.Err),
+		})
+	}
+
+	return out, nil
+}
+
+// Resolve get short URL ID and password of protected link, return redirect target and code,
+// click is counted as for HTTP redirect
+func (s *Server) Resolve(ctx context.Context, in *pb.ResolveRequest) (*pb.ResolveResponse, error) {
+
+	visit := model.Visit{Confirmed: true, IP: peerIP(ctx)}
+	if in.GetPassword() != "" {
+		pw := in.GetPassword()
+		visit.Password = &pw
+	}
+
+	target, code, err := s.s.Resolve(ctx, in.GetId(), visit)
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	return &pb.ResolveResponse{OriginalUrl: target, RedirectCode: int32(code)}, nil
+}
+
+// ListUserURLs return page of user links and cursor of the next page
+func (s *Server) ListUserURLs(ctx context.Context, in *pb.ListUserURLsRequest) (*pb.ListUserURLsResponse, error) {
+
+	userID, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q := model.ListQuery{
+		UserID: userID,
+		Limit:  int(in.GetLimit()),
+		Cursor: in.GetCursor(),
+		Sort:   in.GetSort(),
+		Desc:   in.GetDesc(),
+		Domain: in.GetDomain(),
+		Search: in.GetSearch(),
+		Tag:    in.GetTag(),
+	}
+
+	links, next, err := s.s.List(ctx, q)
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	out := &pb.ListUserURLsResponse{Urls: make([]*pb.UserURL, 0, len(links)), NextCursor: next}
+	for _, val := range links {
+		u := &pb.UserURL{
+			ShortUrl:    s.s.ShortURL(val.Short),
+			OriginalUrl: val.Long,
+			Title:       val.Title,
+			Description: val.Description,
+			Tags:        val.Tags,
+			CreatedAt:   timestamppb.New(val.CreatedAt),
+		}
+		out.Urls = append(out.Urls, u)
+	}
+
+	return out, nil
+}
+
+// DeleteUserURLs mark user URLs as deleted in background, return delete job ID
+func (s *Server) DeleteUserURLs(ctx context.Context, in *pb.DeleteUserURLsRequest) (*pb.DeleteUserURLsResponse, error) {
+
+	userID, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jobID, err := s.s.Delete(ctx, userID, in.GetIds())
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	return &pb.DeleteUserURLsResponse{JobId: jobID}, nil
+}
+
+// Ping check database connection
+func (s *Server) Ping(ctx context.Context, in *pb.PingRequest) (*pb.PingResponse, error) {
+
+	if err := s.rep.Ping.PingDB(); err != nil {
+		s.logger.Error(err)
+		return nil, status.Error(codes.Unavailable, "database is not available")
+	}
+
+	return &pb.PingResponse{}, nil
+}
+
+// linkOptions convert request options to model
+func linkOptions(in *pb.LinkOptions) (model.LinkOptions, model.LinkMeta) {
+
+	opts := model.LinkOptions{
+		RedirectCode:     int(in.GetRedirectCode()),
+		QueryPassthrough: in.GetQueryPassthrough(),
+		MaxClicks:        int(in.GetMaxClicks()),
+		Interstitial:     in.GetInterstitial(),
+		Password:         in.GetPassword(),
+	}
+	if u := in.GetUtm(); u != nil {
+		utm := model.UTM{
+			Source:   u.GetSource(),
+			Medium:   u.GetMedium(),
+			Campaign: u.GetCampaign(),
+			Term:     u.GetTerm(),
+			Content:  u.GetContent(),
+		}
+		if len(utm.Values()) > 0 {
+			opts.UTM = &utm
+		}
+	}
+	for _, r := range in.GetRules() {
+		opts.Rules = append(opts.Rules, model.Rule{Platform: r.GetPlatform(), Language: r.GetLanguage(), Country: r.GetCountry(), URL: r.GetUrl()})
+	}
+	if in.GetExpiresAt() != nil {
+		t := in.GetExpiresAt().AsTime().In(time.UTC)
+		opts.ExpiresAt = &t
+	}
+
+	meta := model.LinkMeta{
+		Title:       in.GetTitle(),
+		Description: in.GetDescription(),
+		Tags:        in.GetTags(),
+	}
+
+	return opts, meta
+}
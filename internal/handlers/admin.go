@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/problem"
+	"github.com/RomanIkonnikov93/URLshortner/internal/service"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+	"github.com/go-chi/chi"
+)
+
+// AdminAuth allow request only with "Authorization: Bearer ADMIN_TOKEN" header
+func AdminAuth(cfg config.Config, logger logging.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			if cfg.AdminToken == "" {
+				WriteError(w, r, logger, problem.Forbidden("admin API is disabled"))
+				return
+			}
+
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.AdminToken)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				WriteError(w, r, logger, problem.Unauthorized("invalid admin token"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// adminActor identify admin in audit log by client address
+func adminActor(r *http.Request) string {
+	return "admin@" + clientIP(r)
+}
+
+// DisableURL get optional reason in JSON format, disable short URL of any user
+func DisableURL(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// read request body
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			WriteError(w, r, logger, problem.InvalidBody(err))
+			return
+		}
+
+		// unmarshal request
+		data := model.DisableRequest{}
+		if len(b) > 0 {
+			err = json.Unmarshal(b, &data)
+			if err != nil {
+				WriteError(w, r, logger, problem.InvalidJSON(err))
+				return
+			}
+		}
+
+		err = s.DisableLink(r.Context(), adminActor(r), chi.URLParam(r, "id"), data.Reason)
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// EnableURL enable disabled short URL
+func EnableURL(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		err := s.EnableLink(r.Context(), adminActor(r), chi.URLParam(r, "id"))
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// BanUser ban user, requests with user token are rejected with 403, unknown user is answered with 404
+func BanUser(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
+	return setBanned(s, logger, true)
+}
+
+// UnbanUser lift ban of user
+func UnbanUser(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
+	return setBanned(s, logger, false)
+}
+
+func setBanned(s *service.Shortener, logger logging.Logger, banned bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		err := s.SetBanned(r.Context(), adminActor(r), chi.URLParam(r, "user"), banned)
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminSearchURLs return page of links of all users in JSON format, filtered by the same parameters
+// as GetAllUserURLs and by owner in user parameter
+func AdminSearchURLs(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		q, err := ListQuery(r.URL.Query())
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+		q.UserID = r.URL.Query().Get("user")
+
+		data, next, err := s.SearchLinks(r.Context(), adminActor(r), q, r.URL.Query())
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		if next != "" {
+			u := *r.URL
+			v := u.Query()
+			v.Set("cursor", next)
+			u.RawQuery = v.Encode()
+			w.Header().Set("Link", `<http://`+r.Host+u.RequestURI()+`>; rel="next"`)
+		}
+
+		arr := make([]model.AdminURLResponse, 0, len(data))
+		for _, val := range data {
+			arr = append(arr, model.AdminURLResponse{
+				Short:          s.ShortURL(val.Short),
+				Long:           val.Long,
+				UserID:         val.UserID,
+				CreatedAt:      val.CreatedAt,
+				Clicks:         val.Clicks,
+				Deleted:        val.Deleted,
+				DisabledAt:     val.DisabledAt,
+				DisabledReason: val.DisabledReason,
+			})
+		}
+
+		// marshal response
+		j, err := json.Marshal(&arr)
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(j)
+	}
+}
+
+// ClearCache drop QR codes cached in memory and return count of dropped images in JSON format
+func ClearCache(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		n, err := s.ClearCache(r.Context(), adminActor(r))
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		// marshal response
+		j, err := json.Marshal(&model.ClearCacheResponse{QRCodes: n})
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(j)
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/auth"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/service"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+	"github.com/go-chi/chi"
+)
+
+// GetDeleteJob get job ID, return status of user delete request in JSON format
+func GetDeleteJob(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := auth.UserID(r.Context())
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		job, err := s.Job(r.Context(), userID, chi.URLParam(r, "job"))
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		// marshal response
+		j, err := json.Marshal(&job)
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(j)
+	}
+}
+
+// DeleteTaggedURLs get tag, mark all user URLs that carry it as deleted in background
+func DeleteTaggedURLs(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := auth.UserID(r.Context())
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		jobID, err := s.DeleteTag(r.Context(), userID, chi.URLParam(r, "tag"))
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		// marshal response
+		j, err := json.Marshal(&model.DeleteJobResponse{JobID: jobID})
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write(j)
+	}
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/problem"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+)
+
+// WriteError log error and write it as application/problem+json response
+func WriteError(w http.ResponseWriter, r *http.Request, logger logging.Logger, err error) {
+
+	p := problem.Write(w, r, err)
+	if p.Status >= http.StatusInternalServerError {
+		logger.Error(err)
+	} else {
+		logger.Warn(err)
+	}
+}
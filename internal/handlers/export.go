@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/auth"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/problem"
+	"github.com/RomanIkonnikov93/URLshortner/internal/service"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+)
+
+// Formats of export
+const (
+	ExportCSV   = "csv"
+	ExportJSON  = "json"
+	ExportJSONL = "jsonl"
+)
+
+// exportFlush rows written between flushes of response
+const exportFlush = 100
+
+// exportWriter write export records in one of formats
+type exportWriter interface {
+	Begin() error
+	Write(rec model.ExportRecord) error
+	Flush()
+	End() error
+}
+
+type csvExport struct {
+	w *csv.Writer
+}
+
+func (e *csvExport) Begin() error {
+	return e.w.Write([]string{"short_url", "original_url", "created_at", "deleted", "expires_at", "clicks"})
+}
+
+func (e *csvExport) Write(rec model.ExportRecord) error {
+	expires := ""
+	if rec.ExpiresAt != nil {
+		expires = rec.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return e.w.Write([]string{
+		rec.ShortURL,
+		rec.OriginalURL,
+		rec.CreatedAt.UTC().Format(time.RFC3339),
+		strconv.FormatBool(rec.Deleted),
+		expires,
+		strconv.FormatInt(rec.Clicks, 10),
+	})
+}
+
+func (e *csvExport) Flush() {
+	e.w.Flush()
+}
+
+func (e *csvExport) End() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonExport struct {
+	w     io.Writer
+	enc   *json.Encoder
+	array bool
+	n     int
+}
+
+func (e *jsonExport) Begin() error {
+	if e.array {
+		_, err := io.WriteString(e.w, "[\n")
+		return err
+	}
+	return nil
+}
+
+func (e *jsonExport) Write(rec model.ExportRecord) error {
+	if e.array && e.n > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.n++
+	return e.enc.Encode(&rec)
+}
+
+func (e *jsonExport) Flush() {}
+
+func (e *jsonExport) End() error {
+	if e.array {
+		_, err := io.WriteString(e.w, "]\n")
+		return err
+	}
+	return nil
+}
+
+// ExportUserURLs stream all user links with metadata in CSV, JSON or JSONL format
+func ExportUserURLs(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := auth.UserID(r.Context())
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = ExportJSON
+		}
+
+		var out exportWriter
+		switch format {
+		case ExportCSV:
+			out = &csvExport{w: csv.NewWriter(w)}
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		case ExportJSON, ExportJSONL:
+			enc := json.NewEncoder(w)
+			enc.SetEscapeHTML(false)
+			out = &jsonExport{w: w, enc: enc, array: format == ExportJSON}
+			if format == ExportJSON {
+				w.Header().Set("Content-Type", "application/json")
+			} else {
+				w.Header().Set("Content-Type", "application/x-ndjson")
+			}
+		default:
+			WriteError(w, r, logger, &problem.Error{Status: http.StatusBadRequest, Code: problem.CodeInvalidOptions, Detail: "format must be csv, json or jsonl"})
+			return
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="urls.`+format+`"`)
+
+		flusher, _ := w.(http.Flusher)
+		w.WriteHeader(http.StatusOK)
+
+		if err := out.Begin(); err != nil {
+			logger.Error(err)
+			return
+		}
+		n := 0
+		err = s.Export(r.Context(), userID, func(link model.Link) error {
+			err := out.Write(model.ExportRecord{
+				ShortURL:    s.ShortURL(link.Short),
+				OriginalURL: link.Long,
+				CreatedAt:   link.CreatedAt,
+				Deleted:     link.Deleted,
+				ExpiresAt:   link.ExpiresAt,
+				Clicks:      link.Clicks,
+			})
+			if err != nil {
+				return err
+			}
+			n++
+			if n%exportFlush == 0 {
+				out.Flush()
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			// headers are already sent, the client gets truncated export
+			logger.Error(err)
+			return
+		}
+		if err := out.End(); err != nil {
+			logger.Error(err)
+		}
+	}
+}
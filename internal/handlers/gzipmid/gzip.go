@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"compress/gzip"
 	"io"
-	"log"
 	"net/http"
 )
 
@@ -17,17 +16,27 @@ func (w GzipWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+// Flush write compressed data buffered so far and flush underlying response, so streamed responses stay streamed
+func (w GzipWriter) Flush() {
+	if f, ok := w.Writer.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func DecompressGZIP(data []byte) ([]byte, error) {
 
 	r, err := gzip.NewReader(bytes.NewReader(data))
 	if err != nil {
-		log.Printf("%v", err)
+		return nil, err
 	}
 	defer r.Close()
 
 	b, err := io.ReadAll(r)
 	if err != nil {
-		log.Printf("%v", err)
+		return nil, err
 	}
 
 	return b, nil
@@ -1,90 +1,118 @@
 package handlers
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
-	"net/url"
 	"strings"
 
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/auth"
 	"github.com/RomanIkonnikov93/URLshortner/internal/model"
-	"github.com/RomanIkonnikov93/URLshortner/internal/repository"
+	"github.com/RomanIkonnikov93/URLshortner/internal/problem"
+	"github.com/RomanIkonnikov93/URLshortner/internal/service"
 	"github.com/RomanIkonnikov93/URLshortner/logging"
+	"github.com/go-chi/chi"
 )
 
-// GetHandler get long URL by short URL
-func GetHandler(rep repository.Pool, logger logging.Logger) http.HandlerFunc {
+// GetHandler get long URL by short URL, /{id}+ or ?preview=1 show preview page instead of redirect,
+// password of protected link is read from X-Link-Password header, from password form sent by POST
+// or from token of preview page continue link
+func GetHandler(s *service.Shortener, cfg config.Config, logger logging.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		b := strings.Trim(r.URL.Path, "/")
-		resp, err := rep.Storage.Get(r.Context(), b)
+		query := r.URL.Query()
+		preview := strings.HasSuffix(b, "+") || query.Get("preview") == "1"
+		b = strings.TrimSuffix(b, "+")
+		_, confirmed := query["confirm"]
+		token := query.Get("token")
+		query.Del("preview")
+		query.Del("confirm")
+		query.Del("token")
+
+		visit := model.Visit{
+			Query:          query,
+			Confirmed:      confirmed,
+			IP:             clientIP(r),
+			UserAgent:      r.UserAgent(),
+			AcceptLanguage: r.Header.Get("Accept-Language"),
+			Token:          token,
+		}
+		if pw, ok := r.Header[http.CanonicalHeaderKey(PasswordHeader)]; ok && len(pw) > 0 {
+			visit.Password = &pw[0]
+		} else if r.Method == http.MethodPost && r.ParseForm() == nil && r.PostForm.Has("password") {
+			pw := r.PostForm.Get("password")
+			visit.Password = &pw
+		}
+
+		if preview {
+			writePreview(w, r, s, cfg, logger, b, visit)
+			return
+		}
+
+		resp, code, err := s.Resolve(r.Context(), b, visit)
 		if err != nil {
-			if err == model.ErrDelFlag {
-				logger.Error(err)
-				http.Error(w, err.Error(), http.StatusGone)
-				return
-			} else {
-				logger.Error(err)
-				http.Error(w, err.Error(), http.StatusNotFound)
+			if errors.Is(err, model.ErrInterstitial) {
+				writePreview(w, r, s, cfg, logger, b, visit)
 				return
 			}
+			writeGetError(w, r, cfg, logger, err)
+			return
+		}
+
+		// 307 and 308 make browser repeat POST with the password to destination
+		if r.Method == http.MethodPost {
+			code = http.StatusSeeOther
 		}
 
 		w.Header().Set("Location", resp)
-		http.Redirect(w, r, resp, http.StatusTemporaryRedirect)
+		http.Redirect(w, r, resp, code)
+	}
+}
+
+// writeGetError redirect to NotFoundRedirect for unknown short URL if set, show password form to browser
+// for protected link, write error otherwise
+func writeGetError(w http.ResponseWriter, r *http.Request, cfg config.Config, logger logging.Logger, err error) {
+	if errors.Is(err, model.ErrNotFound) && cfg.NotFoundRedirect != "" {
+		http.Redirect(w, r, cfg.NotFoundRedirect, http.StatusFound)
+		return
+	}
+	if passwordError(err) && r.Header.Get(PasswordHeader) == "" {
+		writePasswordForm(w, r, logger, err)
+		return
 	}
+	WriteError(w, r, logger, err)
 }
 
 // PostHandler get long URL and return short URL
-func PostHandler(rep repository.Pool, logger logging.Logger) http.HandlerFunc {
+func PostHandler(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		// read request body
 		b, err := io.ReadAll(r.Body)
 		if err != nil {
-			logger.Error(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			WriteError(w, r, logger, problem.InvalidBody(err))
 			return
 		}
 
-		// validate URL function
-		_, err = url.ParseRequestURI(string(b))
+		userID, err := auth.UserID(r.Context())
 		if err != nil {
-			logger.Error(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			WriteError(w, r, logger, err)
 			return
 		}
 
-		// generate short URL
-		sURL := Short()
-		// make response
-		short := "http://" + r.Host + "/" + sURL
-
-		// add userID and URLs in repository
-		userID, ok := r.Context().Value(UserCtx("userID")).(string)
-		if !ok || userID == "" {
-			logger.Printf("userID not exist %v", http.StatusInternalServerError)
-			http.Error(w, "userID not exist", http.StatusInternalServerError)
-			return
-		}
-		if err := rep.Storage.Add(r.Context(), sURL, string(b), userID); err != nil {
+		short, err := s.Shorten(r.Context(), userID, model.URLRequest{URL: string(b)})
+		if err != nil {
 			if errors.Is(err, model.ErrConflict) {
-				s, err := rep.Storage.GetShort(r.Context(), string(b))
-				if err != nil {
-					logger.Error(err)
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
 				logger.Printf("%v", http.StatusConflict)
 				w.WriteHeader(http.StatusConflict)
 				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-				_, _ = w.Write([]byte("http://" + r.Host + "/" + s))
+				_, _ = w.Write([]byte(short))
 				return
 			}
-			logger.Error(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			WriteError(w, r, logger, err)
 			return
 		}
 
@@ -94,15 +122,14 @@ func PostHandler(rep repository.Pool, logger logging.Logger) http.HandlerFunc {
 	}
 }
 
-// PostJSONHandler get long URL in JSON format, return short URL in JSON format
-func PostJSONHandler(rep repository.Pool, logger logging.Logger) http.HandlerFunc {
+// PostJSONHandler get long URL in JSON format, return short URL in JSON format, with ?qr=1 also URL of its QR code
+func PostJSONHandler(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		// read request body
 		b, err := io.ReadAll(r.Body)
 		if err != nil {
-			logger.Error(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			WriteError(w, r, logger, problem.InvalidBody(err))
 			return
 		}
 
@@ -110,121 +137,90 @@ func PostJSONHandler(rep repository.Pool, logger logging.Logger) http.HandlerFun
 		data := model.URLRequest{}
 		err = json.Unmarshal(b, &data)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			WriteError(w, r, logger, problem.InvalidJSON(err))
 			return
 		}
 
-		// set that problem HTML characters should not be escaped
-		buf := bytes.NewBuffer([]byte{})
-		encoder := json.NewEncoder(buf)
-		encoder.SetEscapeHTML(false)
-		err = encoder.Encode(data.URL)
+		userID, err := auth.UserID(r.Context())
 		if err != nil {
-			logger.Error(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			WriteError(w, r, logger, err)
 			return
 		}
 
-		// validate URL function
-		_, err = url.ParseRequestURI(data.URL)
+		status := http.StatusCreated
+		short, err := s.Shorten(r.Context(), userID, data)
 		if err != nil {
-			logger.Error(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// set a value to response
-		res := model.URLResponse{}
-		res.Result = buf.String()
-
-		// generate short URL
-		sURL := Short()
-		// make response
-		short := "http://" + r.Host + "/" + sURL
-
-		// add userID and URLs in repository
-		userID, ok := r.Context().Value(UserCtx("userID")).(string)
-		if !ok || userID == "" {
-			logger.Printf("userID not exist %v", http.StatusInternalServerError)
-			http.Error(w, "userID not exist", http.StatusInternalServerError)
-			return
-		}
-		if err := rep.Storage.Add(r.Context(), sURL, data.URL, userID); err != nil {
-			if errors.Is(err, model.ErrConflict) {
-				s, err := rep.Storage.GetShort(r.Context(), data.URL)
-				if err != nil {
-					logger.Error(err)
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-				res.Result = "http://" + r.Host + "/" + s
-				j, err := json.Marshal(&res)
-				if err != nil {
-					logger.Error(err)
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-				logger.Printf("%v", http.StatusConflict)
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusConflict)
-				_, _ = w.Write(j)
+			if !errors.Is(err, model.ErrConflict) {
+				WriteError(w, r, logger, err)
 				return
 			}
-			logger.Error(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			logger.Printf("%v", http.StatusConflict)
+			status = http.StatusConflict
 		}
 
 		// marshal response
-		res.Result = short
+		res := model.URLResponse{Result: short}
+		if wantQR(r) {
+			res.QRURL = service.QRURL(short)
+		}
 		j, err := json.Marshal(&res)
 		if err != nil {
-			logger.Error(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			WriteError(w, r, logger, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
+		w.WriteHeader(status)
 		_, _ = w.Write(j)
 	}
 }
 
-// GetAllUserURLs get userID, return all User short and long URLs in JSON format
-func GetAllUserURLs(repository repository.Pool, logger logging.Logger) http.HandlerFunc {
+// GetAllUserURLs get userID, return page of User short and long URLs in JSON format,
+// link to the next page is sent in Link header
+func GetAllUserURLs(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		userID, ok := r.Context().Value(UserCtx("userID")).(string)
-		if !ok || userID == "" {
-			logger.Printf("userID not exist %v", http.StatusInternalServerError)
-			http.Error(w, "userID not exist", http.StatusInternalServerError)
+		userID, err := auth.UserID(r.Context())
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		q, err := ListQuery(r.URL.Query())
+		if err != nil {
+			WriteError(w, r, logger, err)
 			return
 		}
-		data, err := repository.Storage.GetByUserID(r.Context(), userID)
+		q.UserID = userID
+
+		data, next, err := s.List(r.Context(), q)
 		if err != nil {
-			logger.Error(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			WriteError(w, r, logger, err)
 			return
 		}
 
+		if next != "" {
+			u := *r.URL
+			v := u.Query()
+			v.Set("cursor", next)
+			u.RawQuery = v.Encode()
+			w.Header().Set("Link", `<http://`+r.Host+u.RequestURI()+`>; rel="next"`)
+		}
+
 		// make response structure
 		if len(data) == 0 {
 			logger.Printf("%v", http.StatusNoContent)
 			w.WriteHeader(http.StatusNoContent)
 		} else {
-			var arr []*model.URLsJSONResponse
-			for s, l := range data {
-				res := new(model.URLsJSONResponse)
-				res.Short = "http://" + r.Host + "/" + s
-				res.Long = l
-				arr = append(arr, res)
+			arr := make([]*model.URLsJSONResponse, 0, len(data))
+			for _, val := range data {
+				arr = append(arr, LinkResponse(s, val))
 			}
 
 			// marshal response
 			j, err := json.Marshal(&arr)
 			if err != nil {
-				logger.Error(err)
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				WriteError(w, r, logger, err)
 				return
 			}
 
@@ -235,88 +231,96 @@ func GetAllUserURLs(repository repository.Pool, logger logging.Logger) http.Hand
 	}
 }
 
-// PostBatchHandler get batch URLs in JSON format, return many short URLs in JSON format
-func PostBatchHandler(rep repository.Pool, logger logging.Logger) http.HandlerFunc {
+// UpdateUserURL get new target and options in JSON format, change user short URL
+func UpdateUserURL(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
+		userID, err := auth.UserID(r.Context())
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
 		// read request body
 		b, err := io.ReadAll(r.Body)
 		if err != nil {
-			logger.Error(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			WriteError(w, r, logger, problem.InvalidBody(err))
 			return
 		}
 
 		// unmarshal request
-		data := model.BatchRequest{}
+		data := model.URLUpdateRequest{}
 		err = json.Unmarshal(b, &data)
 		if err != nil {
-			logger.Error(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			WriteError(w, r, logger, problem.InvalidJSON(err))
 			return
 		}
 
-		// range data response
-		var arr []*model.BatchResponse
-		for _, val := range data {
+		link, err := s.Update(r.Context(), userID, chi.URLParam(r, "id"), data)
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
 
-			// set that problem HTML characters should not be escaped
-			buf := bytes.NewBuffer([]byte{})
-			encoder := json.NewEncoder(buf)
-			encoder.SetEscapeHTML(false)
-			err = encoder.Encode(val.OriginalURL)
-			if err != nil {
-				logger.Error(err)
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
+		// marshal response
+		res := LinkResponse(s, link)
+		j, err := json.Marshal(res)
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
 
-			// validate URL function
-			_, err = url.ParseRequestURI(val.OriginalURL)
-			if err != nil {
-				logger.Error(err)
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(j)
+	}
+}
 
-			// set a value to response
-			val.OriginalURL = buf.String()
-			long := strings.Trim(val.OriginalURL, "\"\n")
-			// generate short URL
-			sURL := Short()
-
-			// add userID and URLs in repository
-			userID, ok := r.Context().Value(UserCtx("userID")).(string)
-			if !ok || userID == "" {
-				logger.Printf("userID not exist %v", http.StatusInternalServerError)
-				http.Error(w, "userID not exist", http.StatusInternalServerError)
-				return
+// PostBatchHandler get batch URLs in JSON format, add them in one transaction, return short URL and status of each in JSON format,
+// with ?qr=1 also URL of QR code of each
+func PostBatchHandler(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// read request body
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			WriteError(w, r, logger, problem.InvalidBody(err))
+			return
+		}
+
+		// unmarshal request
+		data := model.BatchRequest{}
+		err = json.Unmarshal(b, &data)
+		if err != nil {
+			WriteError(w, r, logger, problem.InvalidJSON(err))
+			return
+		}
+
+		userID, err := auth.UserID(r.Context())
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		arr, err := s.ShortenBatch(r.Context(), userID, data)
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+		qr := wantQR(r)
+		for _, val := range arr {
+			if val.Err != nil {
+				val.Error = problem.From(val.Err).Detail
 			}
-			if err := rep.Storage.Add(r.Context(), sURL, long, userID); err != nil {
-				if errors.Is(err, model.ErrConflict) {
-					s, err := rep.Storage.GetShort(r.Context(), long)
-					if err != nil {
-						logger.Error(err)
-						http.Error(w, err.Error(), http.StatusInternalServerError)
-						return
-					}
-					sURL = s
-				}
+			if qr && val.ShortURL != "" {
+				val.QRURL = service.QRURL(val.ShortURL)
 			}
-
-			// make response
-			short := "http://" + r.Host + "/" + sURL
-			res := new(model.BatchResponse)
-			res.ShortURL = short
-			res.CorrelationID = val.CorrelationID
-			arr = append(arr, res)
 		}
 
 		// marshal response
 		j, err := json.Marshal(&arr)
 		if err != nil {
-			logger.Error(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			WriteError(w, r, logger, err)
 			return
 		}
 
@@ -327,42 +331,90 @@ func PostBatchHandler(rep repository.Pool, logger logging.Logger) http.HandlerFu
 }
 
 // DeleteUserURLs get batch short URLs ID in JSON format, changes the status in the database to deleted
-func DeleteUserURLs(rep repository.Pool, logger logging.Logger) http.HandlerFunc {
+func DeleteUserURLs(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		// read request body
 		b, err := io.ReadAll(r.Body)
 		if err != nil {
-			logger.Error(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			WriteError(w, r, logger, problem.InvalidBody(err))
 			return
 		}
 
 		// add userID and URLs in repository
-		userID, ok := r.Context().Value(UserCtx("userID")).(string)
-		if !ok || userID == "" {
-			logger.Printf("userID not exist %v", http.StatusInternalServerError)
-			http.Error(w, "userID not exist", http.StatusInternalServerError)
+		userID, err := auth.UserID(r.Context())
+		if err != nil {
+			WriteError(w, r, logger, err)
 			return
 		}
 
 		//unmarshal request
-		data := model.UserRequest{
-			UserID: userID,
+		var ids []string
+		err = json.Unmarshal(b, &ids)
+		if err != nil {
+			WriteError(w, r, logger, problem.InvalidJSON(err))
+			return
 		}
 
-		err = json.Unmarshal(b, &data.UserUrls)
+		jobID, err := s.Delete(r.Context(), userID, ids)
 		if err != nil {
-			logger.Error(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			WriteError(w, r, logger, err)
 			return
 		}
 
-		go func() {
-			err = rep.Storage.BatchDelete(data)
-			logger.Error(err)
-		}()
+		// marshal response
+		j, err := json.Marshal(&model.DeleteJobResponse{JobID: jobID})
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
 
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write(j)
+	}
+}
+
+// RestoreUserURLs get batch short URLs ID in JSON format, restore URLs deleted within retention window
+func RestoreUserURLs(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// read request body
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			WriteError(w, r, logger, problem.InvalidBody(err))
+			return
+		}
+
+		userID, err := auth.UserID(r.Context())
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		//unmarshal request
+		var ids []string
+		err = json.Unmarshal(b, &ids)
+		if err != nil {
+			WriteError(w, r, logger, problem.InvalidJSON(err))
+			return
+		}
+
+		restored, err := s.Restore(r.Context(), userID, ids)
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		// marshal response
+		j, err := json.Marshal(&restored)
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(j)
 	}
 }
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/auth"
+	"github.com/RomanIkonnikov93/URLshortner/internal/importer"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/problem"
+	"github.com/RomanIkonnikov93/URLshortner/internal/service"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+)
+
+// ImportFormat get import format from query parameter or Content-Type
+func ImportFormat(r *http.Request) string {
+
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f
+	}
+
+	t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch t {
+	case "text/csv":
+		return importer.FormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return importer.FormatJSONL
+	}
+	return ""
+}
+
+// ImportUserURLs get CSV or JSONL with long URLs, stream result of every line in JSONL format
+func ImportUserURLs(s *service.Shortener, cfg config.Config, logger logging.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := auth.UserID(r.Context())
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		format := ImportFormat(r)
+		if format != importer.FormatCSV && format != importer.FormatJSONL {
+			WriteError(w, r, logger, &problem.Error{Status: http.StatusUnsupportedMediaType, Code: problem.CodeInvalidBody, Detail: importer.ErrFormat.Error()})
+			return
+		}
+
+		writeImport(w, r, logger, format, cfg, func(ctx context.Context, lines []importer.Line) ([]model.ImportResult, error) {
+			return s.Import(ctx, userID, lines)
+		})
+	}
+}
+
+// importFunc add chunk of parsed import lines and return result of each line
+type importFunc func(ctx context.Context, lines []importer.Line) ([]model.ImportResult, error)
+
+// writeImport pass request body to fn by chunks of cfg.ImportChunkSize lines and stream results,
+// body is spooled to temporary file first because HTTP/1 server closes it once response is flushed
+func writeImport(w http.ResponseWriter, r *http.Request, logger logging.Logger, format string, cfg config.Config, fn importFunc) {
+
+	src := r.Body
+	if cfg.ImportMaxBytes > 0 {
+		src = http.MaxBytesReader(w, r.Body, cfg.ImportMaxBytes)
+	}
+	body, lines, err := spoolBody(src)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			WriteError(w, r, logger, problem.BodyTooLarge(err))
+			return
+		}
+		WriteError(w, r, logger, problem.InvalidBody(err))
+		return
+	}
+	defer body.Close()
+
+	if cfg.ImportMaxLines > 0 && lines > cfg.ImportMaxLines {
+		WriteError(w, r, logger, fmt.Errorf("%w: at most %d lines are allowed", model.ErrBatchTooLarge, cfg.ImportMaxLines))
+		return
+	}
+
+	ctx := service.ImportContext(r.Context())
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	started := false
+
+	err = importer.Read(body, format, cfg.ImportChunkSize, func(lines []importer.Line) error {
+		res, err := fn(ctx, lines)
+		if err != nil {
+			return err
+		}
+
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+		}
+		for _, val := range res {
+			if val.Err != nil {
+				val.Error = problem.From(val.Err).Detail
+			}
+			if err := encoder.Encode(&val); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if started {
+			logger.Error(err)
+			return
+		}
+		if errors.Is(err, importer.ErrFormat) {
+			err = problem.InvalidBody(err)
+		}
+		WriteError(w, r, logger, err)
+		return
+	}
+
+	if !started {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// spooledBody temporary file removed on Close
+type spooledBody struct {
+	*os.File
+}
+
+func (b spooledBody) Close() error {
+	err := b.File.Close()
+	if rmErr := os.Remove(b.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// lineCounter count lines of written bytes, last line may have no line break
+type lineCounter struct {
+	lines int
+	last  byte
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		c.lines += bytes.Count(p, []byte{'\n'})
+		c.last = p[len(p)-1]
+	}
+	return len(p), nil
+}
+
+func (c *lineCounter) count() int {
+	if c.last != 0 && c.last != '\n' {
+		return c.lines + 1
+	}
+	return c.lines
+}
+
+// spoolBody copy request body to temporary file and return it for reading from the start with count of its lines,
+// the file is removed on any error
+func spoolBody(body io.Reader) (io.ReadCloser, int, error) {
+
+	f, err := os.CreateTemp("", "import-*")
+	if err != nil {
+		return nil, 0, err
+	}
+	b := spooledBody{f}
+
+	var counter lineCounter
+	if _, err := io.Copy(io.MultiWriter(f, &counter), body); err != nil {
+		b.Close()
+		return nil, 0, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		b.Close()
+		return nil, 0, err
+	}
+
+	return b, counter.count(), nil
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/importer"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/problem"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+	"github.com/sirupsen/logrus"
+)
+
+func TestWriteImportReadsAllChunks(t *testing.T) {
+
+	const chunk, lines = 100, 2550
+
+	logger := logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeImport(w, r, logger, importer.FormatJSONL, config.Config{ImportChunkSize: chunk}, func(ctx context.Context, in []importer.Line) ([]model.ImportResult, error) {
+			calls++
+			out := make([]model.ImportResult, len(in))
+			for i, line := range in {
+				out[i] = model.ImportResult{Line: line.N, Status: model.StatusCreated, ShortURL: "http://short/" + line.Record.Alias}
+			}
+			return out, nil
+		})
+	}))
+	defer srv.Close()
+
+	var body strings.Builder
+	for i := 1; i <= lines; i++ {
+		fmt.Fprintf(&body, "{\"url\":\"https://example.com/%d\",\"alias\":\"a%d\"}\n", i, i)
+	}
+
+	resp, err := http.Post(srv.URL, "application/x-ndjson", strings.NewReader(body.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	n := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var res model.ImportResult
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		n++
+		if res.Line != n || res.ShortURL != fmt.Sprintf("http://short/a%d", n) {
+			t.Fatalf("result %d = %+v", n, res)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n != lines {
+		t.Fatalf("got %d results, want %d", n, lines)
+	}
+	if want := (lines + chunk - 1) / chunk; calls != want {
+		t.Fatalf("import called %d times, want %d", calls, want)
+	}
+}
+
+func TestWriteImportLimits(t *testing.T) {
+
+	line := "{\"url\":\"https://example.com/\"}\n"
+	tests := []struct {
+		name string
+		cfg  config.Config
+		body string
+		code string
+	}{
+		{"body too large", config.Config{ImportMaxBytes: 1024}, strings.Repeat(line, 100), problem.CodeBodyTooLarge},
+		{"too many lines", config.Config{ImportMaxLines: 10}, strings.Repeat(line, 11), problem.CodeBatchTooLarge},
+		{"too many lines without last line break", config.Config{ImportMaxLines: 10}, strings.Repeat(line, 10) + "{}", problem.CodeBatchTooLarge},
+	}
+
+	logger := logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.ImportChunkSize = 10
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeImport(w, r, logger, importer.FormatJSONL, tt.cfg, func(ctx context.Context, in []importer.Line) ([]model.ImportResult, error) {
+					t.Error("import must not be called")
+					return nil, nil
+				})
+			}))
+			defer srv.Close()
+
+			resp, err := http.Post(srv.URL, "application/x-ndjson", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusRequestEntityTooLarge)
+			}
+			var p problem.Problem
+			if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Code != tt.code {
+				t.Fatalf("code = %q, want %q", p.Code, tt.code)
+			}
+		})
+	}
+}
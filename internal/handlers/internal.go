@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/problem"
+	"github.com/RomanIkonnikov93/URLshortner/internal/service"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+)
+
+// TrustedSubnet allow request only if X-Real-IP is within TRUSTED_SUBNET, 403 otherwise
+func TrustedSubnet(cfg config.Config, logger logging.Logger) func(next http.Handler) http.Handler {
+
+	var subnet *net.IPNet
+	if cfg.TrustedSubnet != "" {
+		var err error
+		_, subnet, err = net.ParseCIDR(cfg.TrustedSubnet)
+		if err != nil {
+			logger.Errorf("TRUSTED_SUBNET: %s", err)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			ip := net.ParseIP(r.Header.Get("X-Real-IP"))
+			if subnet == nil || ip == nil || !subnet.Contains(ip) {
+				WriteError(w, r, logger, problem.Forbidden("client is not in trusted subnet"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetStats return counts of URLs and users in JSON format
+func GetStats(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		st, err := s.Stats(r.Context())
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		// marshal response
+		j, err := json.Marshal(&st)
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(j)
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+// ListQuery parse limit, cursor, sort, order, deleted, expired, domain, tag and q parameters of links list,
+// values are validated by service
+func ListQuery(v url.Values) (model.ListQuery, error) {
+
+	q := model.ListQuery{
+		Cursor: v.Get("cursor"),
+		Sort:   v.Get("sort"),
+		Domain: v.Get("domain"),
+		Search: v.Get("q"),
+		Tag:    v.Get("tag"),
+	}
+
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return q, listError("limit must be from 1 to " + strconv.Itoa(model.MaxPageSize))
+		}
+		q.Limit = n
+	}
+
+	switch v.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return q, listError("order must be asc or desc")
+	}
+
+	var err error
+	if q.Deleted, err = boolParam(v, "deleted"); err != nil {
+		return q, err
+	}
+	if q.Expired, err = boolParam(v, "expired"); err != nil {
+		return q, err
+	}
+
+	return q, nil
+}
+
+func boolParam(v url.Values, name string) (*bool, error) {
+	s := v.Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, listError(name + " must be true or false")
+	}
+	return &b, nil
+}
+
+func listError(detail string) error {
+	return fmt.Errorf("%w: %s", model.ErrListQuery, detail)
+}
@@ -2,13 +2,14 @@ package handlers
 
 import (
 	"compress/gzip"
-	"context"
 	"io"
 	"net/http"
 	"strings"
 
+	"github.com/RomanIkonnikov93/URLshortner/internal/auth"
 	"github.com/RomanIkonnikov93/URLshortner/internal/handlers/gzipmid"
 	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/problem"
 	"github.com/RomanIkonnikov93/URLshortner/internal/repository"
 	"github.com/RomanIkonnikov93/URLshortner/logging"
 )
@@ -39,14 +40,12 @@ func GzipRequest(next http.Handler) http.Handler {
 		if r.Header.Get("Content-Encoding") == "gzip" {
 			b, err := io.ReadAll(r.Body)
 			if err != nil {
-				logger.Error(err)
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				WriteError(w, r, *logger, problem.InvalidBody(err))
 				return
 			}
 			data, err := gzipmid.DecompressGZIP(b)
 			if err != nil {
-				logger.Error(err)
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				WriteError(w, r, *logger, problem.InvalidBody(err))
 				return
 			}
 			r.Body = io.NopCloser(strings.NewReader(string(data)))
@@ -74,7 +73,7 @@ func UserValidation(rep repository.Pool, logger logging.Logger) func(next http.H
 			}
 
 			c := cookie.Value
-			userID, err := CheckUSerCookies(c, model.Key, rep)
+			userID, err := auth.CheckToken(c, model.Key, rep)
 			if err != nil || userID == "" {
 				ctx, err := SetUserCtx(w, r, model.Key, rep)
 				if err != nil {
@@ -85,7 +84,16 @@ func UserValidation(rep repository.Pool, logger logging.Logger) func(next http.H
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), UserCtx("userID"), userID)
+			banned, err := rep.Users.Banned(userID)
+			if err != nil {
+				logger.Error(err)
+			}
+			if banned {
+				WriteError(w, r, logger, model.ErrBanned)
+				return
+			}
+
+			ctx := auth.WithUserID(r.Context(), userID)
 			r = r.WithContext(ctx)
 			next.ServeHTTP(w, r)
 		})
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/problem"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+)
+
+// PasswordHeader of request to protected link, error is returned as problem instead of password form
+const PasswordHeader = "X-Link-Password"
+
+// passwordPage data of password.html
+type passwordPage struct {
+	Action string
+	Error  string
+}
+
+// passwordError check if error is answered with password form
+func passwordError(err error) bool {
+	return errors.Is(err, model.ErrPasswordRequired) || errors.Is(err, model.ErrPasswordWrong) || errors.Is(err, model.ErrTooManyAttempts)
+}
+
+// writePasswordForm render password form posted back to the same URL
+func writePasswordForm(w http.ResponseWriter, r *http.Request, logger logging.Logger, err error) {
+
+	p := problem.From(err)
+	logger.Warn(err)
+
+	page := passwordPage{Action: r.URL.RequestURI()}
+	if !errors.Is(err, model.ErrPasswordRequired) {
+		page.Error = p.Detail
+	}
+
+	writePage(w, r, logger, p.Status, "password.html", page)
+}
+
+// clientIPKey context key of client address set by ClientIP
+type clientIPKey struct{}
+
+// ClientIP put client address into request context, X-Real-IP is trusted only from TRUSTED_PROXIES
+func ClientIP(cfg config.Config, logger logging.Logger) func(next http.Handler) http.Handler {
+
+	proxies := make([]*net.IPNet, 0, len(cfg.TrustedProxies))
+	for _, val := range cfg.TrustedProxies {
+		_, subnet, err := net.ParseCIDR(strings.TrimSpace(val))
+		if err != nil {
+			logger.Errorf("TRUSTED_PROXIES: %s", err)
+			continue
+		}
+		proxies = append(proxies, subnet)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			ip := r.RemoteAddr
+			if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+				ip = host
+			}
+			if xri := net.ParseIP(r.Header.Get("X-Real-IP")); xri != nil && trusted(proxies, net.ParseIP(ip)) {
+				ip = xri.String()
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+		})
+	}
+}
+
+func trusted(proxies []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, val := range proxies {
+		if val.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP get client address set by ClientIP, connection address if middleware is not used
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/service"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var templates = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
+
+// previewPage data of preview.html
+type previewPage struct {
+	model.Preview
+	Continue string
+}
+
+// writePreview render preview page of link, continue link confirms redirect and keeps query
+// and password token of protected link
+func writePreview(w http.ResponseWriter, r *http.Request, s *service.Shortener, cfg config.Config, logger logging.Logger, id string, visit model.Visit) {
+
+	p, err := s.Preview(r.Context(), id, visit)
+	if err != nil {
+		writeGetError(w, r, cfg, logger, err)
+		return
+	}
+
+	q := url.Values{}
+	for key, val := range visit.Query {
+		q[key] = val
+	}
+	q.Set("confirm", "1")
+	if p.Token != "" {
+		q.Set("token", p.Token)
+	}
+
+	writePage(w, r, logger, http.StatusOK, "preview.html", previewPage{Preview: p, Continue: "/" + id + "?" + q.Encode()})
+}
+
+// writePage render embedded template
+func writePage(w http.ResponseWriter, r *http.Request, logger logging.Logger, status int, name string, data interface{}) {
+
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		WriteError(w, r, logger, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+}
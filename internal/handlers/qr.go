@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/problem"
+	"github.com/RomanIkonnikov93/URLshortner/internal/qr"
+	"github.com/RomanIkonnikov93/URLshortner/internal/service"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+	"github.com/go-chi/chi"
+)
+
+// GetQR return QR code of short URL in PNG or SVG format with size, level and margin from query
+func GetQR(s *service.Shortener, logger logging.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		o, err := qr.ParseOptions(r.URL.Query())
+		if err != nil {
+			WriteError(w, r, logger, &problem.Error{Status: http.StatusBadRequest, Code: problem.CodeInvalidOptions, Detail: err.Error()})
+			return
+		}
+
+		data, err := s.QR(r.Context(), chi.URLParam(r, "id"), o)
+		if err != nil {
+			WriteError(w, r, logger, err)
+			return
+		}
+
+		// no Content-Length, body may be compressed by GzipResponse;
+		// short max-age so QR codes of disabled or deleted links stop being served soon
+		w.Header().Set("Content-Type", o.ContentType())
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	}
+}
+
+// wantQR check qr query parameter of shorten requests
+func wantQR(r *http.Request) bool {
+	b, _ := strconv.ParseBool(r.URL.Query().Get("qr"))
+	return b
+}
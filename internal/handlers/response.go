@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/service"
+)
+
+// LinkResponse make user link with metadata for response
+func LinkResponse(s *service.Shortener, link model.Link) *model.URLsJSONResponse {
+
+	res := &model.URLsJSONResponse{
+		Short:      s.ShortURL(link.Short),
+		Long:       link.Long,
+		UpdatedAt:  link.UpdatedAt,
+		DeletedAt:  link.DeletedAt,
+		ClicksLeft: link.ClicksLeft,
+		LinkMeta:   link.LinkMeta,
+	}
+	if !link.CreatedAt.IsZero() {
+		res.CreatedAt = &link.CreatedAt
+	}
+
+	return res
+}
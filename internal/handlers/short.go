@@ -1,19 +0,0 @@
-package handlers
-
-import (
-	"math/rand"
-)
-
-// Const for func Short
-const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890"
-
-// Short generate short URL
-func Short() string {
-
-	b := make([]byte, 5)
-	for i := range b {
-		b[i] = letterBytes[rand.Intn(len(letterBytes))]
-	}
-
-	return string(b)
-}
@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+// Formats of import file
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// maxLine limit length of one JSONL line
+const maxLine = 1 << 20
+
+var ErrFormat = errors.New("import format must be csv or jsonl")
+
+// Line structure for one parsed line of import file
+type Line struct {
+	N      int
+	Record model.ImportRecord
+	Err    error
+}
+
+// Read stream-parse CSV (url, alias, expires_at) or JSONL records and pass them to fn by chunks of size n
+func Read(r io.Reader, format string, n int, fn func([]Line) error) error {
+
+	if n <= 0 {
+		n = 1
+	}
+
+	chunk := make([]Line, 0, n)
+	add := func(line Line) error {
+		chunk = append(chunk, line)
+		if len(chunk) < n {
+			return nil
+		}
+		err := fn(chunk)
+		chunk = make([]Line, 0, n)
+		return err
+	}
+
+	var err error
+	switch format {
+	case FormatCSV:
+		err = readCSV(r, add)
+	case FormatJSONL:
+		err = readJSONL(r, add)
+	default:
+		return ErrFormat
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(chunk) > 0 {
+		return fn(chunk)
+	}
+	return nil
+}
+
+func readCSV(r io.Reader, add func(Line) error) error {
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	first := true
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			if !errors.As(err, &perr) {
+				return err
+			}
+			if err := add(Line{N: perr.Line, Err: perr.Err}); err != nil {
+				return err
+			}
+			continue
+		}
+		n, _ := reader.FieldPos(0)
+
+		// skip header
+		if first {
+			first = false
+			switch strings.ToLower(fields[0]) {
+			case "url", "long_url", "original_url":
+				continue
+			}
+		}
+
+		line := Line{N: n}
+		line.Record.URL = fields[0]
+		if len(fields) > 1 {
+			line.Record.Alias = fields[1]
+		}
+		if len(fields) > 2 && fields[2] != "" {
+			t, err := time.Parse(time.RFC3339, fields[2])
+			if err != nil {
+				line.Err = fmt.Errorf("expires_at: %w", err)
+			}
+			line.Record.ExpiresAt = &t
+		}
+		if err := add(line); err != nil {
+			return err
+		}
+	}
+}
+
+func readJSONL(r io.Reader, add func(Line) error) error {
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+
+	n := 0
+	for scanner.Scan() {
+		n++
+		b := scanner.Bytes()
+		if len(strings.TrimSpace(string(b))) == 0 {
+			continue
+		}
+
+		line := Line{N: n}
+		line.Err = json.Unmarshal(b, &line.Record)
+		if err := add(line); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
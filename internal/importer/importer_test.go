@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// result of one line in tests
+type result struct {
+	n     int
+	url   string
+	alias string
+	err   bool
+}
+
+func TestRead(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		want   []result
+	}{
+		{
+			name:   "csv with header",
+			format: FormatCSV,
+			data:   "url,alias,expires_at\nhttps://a.example/,a1,\nhttps://b.example/\n",
+			want:   []result{{n: 2, url: "https://a.example/", alias: "a1"}, {n: 3, url: "https://b.example/"}},
+		},
+		{
+			name:   "csv without header",
+			format: FormatCSV,
+			data:   "https://a.example/, a1\n",
+			want:   []result{{n: 1, url: "https://a.example/", alias: "a1"}},
+		},
+		{
+			name:   "csv bad expiry",
+			format: FormatCSV,
+			data:   "https://a.example/,,tomorrow\nhttps://b.example/,,2030-01-02T03:04:05Z\n",
+			want:   []result{{n: 1, url: "https://a.example/", err: true}, {n: 2, url: "https://b.example/"}},
+		},
+		{
+			name:   "csv bad quote",
+			format: FormatCSV,
+			data:   "https://a.example/\n\"https://b\"x\",\nhttps://c.example/\n",
+			want:   []result{{n: 1, url: "https://a.example/"}, {n: 2, err: true}, {n: 3, url: "https://c.example/"}},
+		},
+		{
+			name:   "jsonl",
+			format: FormatJSONL,
+			data:   "{\"url\":\"https://a.example/\",\"alias\":\"a1\"}\n\n{bad}\n{\"url\":\"https://b.example/\"}",
+			want:   []result{{n: 1, url: "https://a.example/", alias: "a1"}, {n: 3, err: true}, {n: 4, url: "https://b.example/"}},
+		},
+		{
+			name:   "empty",
+			format: FormatJSONL,
+			data:   "",
+			want:   []result{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]result, 0)
+			err := Read(strings.NewReader(tt.data), tt.format, 10, func(lines []Line) error {
+				for _, l := range lines {
+					got = append(got, result{n: l.N, url: l.Record.URL, alias: l.Record.Alias, err: l.Err != nil})
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d lines %+v, want %+v", len(got), got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("line %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadChunks(t *testing.T) {
+
+	data := strings.Repeat("https://a.example/\n", 7)
+
+	sizes := make([]int, 0)
+	err := Read(strings.NewReader(data), FormatCSV, 3, func(lines []Line) error {
+		sizes = append(sizes, len(lines))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 3 || sizes[0] != 3 || sizes[1] != 3 || sizes[2] != 1 {
+		t.Fatalf("chunk sizes = %v, want [3 3 1]", sizes)
+	}
+}
+
+func TestReadStopsOnError(t *testing.T) {
+
+	stop := errors.New("stop")
+	calls := 0
+	err := Read(strings.NewReader(strings.Repeat("https://a.example/\n", 5)), FormatCSV, 2, func(lines []Line) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("Read error = %v after %d calls, want %v after 1", err, calls, stop)
+	}
+}
+
+func TestReadFormat(t *testing.T) {
+
+	err := Read(strings.NewReader("x"), "xml", 1, func([]Line) error { return nil })
+	if !errors.Is(err, ErrFormat) {
+		t.Fatalf("Read error = %v, want %v", err, ErrFormat)
+	}
+}
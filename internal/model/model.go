@@ -1,7 +1,9 @@
 package model
 
 import (
+	"encoding/json"
 	"errors"
+	"net/url"
 	"time"
 )
 
@@ -9,38 +11,383 @@ import (
 var Key = []byte{42, 166, 161, 254, 64, 82, 58, 84, 65, 191, 62, 76, 181, 24, 199, 74}
 
 var (
-	ErrConflict = errors.New("conflict on insert")
-	ErrDelFlag  = errors.New("url is deleted")
+	ErrConflict  = errors.New("conflict on insert")
+	ErrDelFlag   = errors.New("url is deleted")
+	ErrExpired   = errors.New("url is expired")
+	ErrNotFound  = errors.New("url not found")
+	ErrDisabled  = errors.New("url is disabled by administrator")
+	ErrExhausted = errors.New("url reached its click limit")
+	ErrBanned    = errors.New("user is banned")
+	ErrNoUserID  = errors.New("userID not exist")
+
+	// ErrInvalidURL wraps error of long URL parse
+	ErrInvalidURL = errors.New("invalid url")
+	// ErrBatchTooLarge wraps limit of batch size
+	ErrBatchTooLarge = errors.New("batch is too large")
+	// ErrImportLine wraps parse error of import line
+	ErrImportLine = errors.New("invalid import line")
+	// ErrListQuery wraps invalid parameter of links list
+	ErrListQuery = errors.New("invalid list query")
+
+	// ErrInterstitial returned by redirect of link that must be previewed first
+	ErrInterstitial = errors.New("url must be previewed before redirect")
+
+	ErrPassword         = errors.New("password must be at most 72 bytes")
+	ErrPasswordRequired = errors.New("url is protected by password")
+	ErrPasswordWrong    = errors.New("wrong password")
+	ErrTooManyAttempts  = errors.New("too many wrong passwords, try again later")
+
+	ErrRedirectCode = errors.New("redirect code must be one of 301, 302, 307, 308")
+	ErrExpiresAt    = errors.New("expires_at must be in the future")
+	ErrAlias        = errors.New("alias must be 3-32 letters, digits, '-' or '_'")
+	ErrAliasTaken   = errors.New("alias is already taken")
+	ErrCursor       = errors.New("invalid cursor")
+	ErrTitle        = errors.New("title must be at most 200 characters")
+	ErrDescription  = errors.New("description must be at most 2000 characters")
+	ErrTags         = errors.New("at most 20 tags of 1-64 characters are allowed")
+	ErrMaxClicks    = errors.New("max_clicks must not be negative")
+	ErrRules        = errors.New("at most 20 rules with url and platform ios, android or desktop, language or 2-letter country are allowed")
 )
 
 const TimeOut = time.Second * 5
 
+// Modes of long URLs deduplication
+const (
+	DedupGlobal = "global"
+	DedupUser   = "user"
+	DedupNone   = "none"
+)
+
+// DefaultRedirectCode used when link has no redirect code
+const DefaultRedirectCode = 307
+
+// UTM structure for UTM parameters appended to the redirect target
+type UTM struct {
+	Source   string `json:"utm_source,omitempty"`
+	Medium   string `json:"utm_medium,omitempty"`
+	Campaign string `json:"utm_campaign,omitempty"`
+	Term     string `json:"utm_term,omitempty"`
+	Content  string `json:"utm_content,omitempty"`
+}
+
+// Values return UTM as query parameters, empty fields are skipped
+func (u UTM) Values() url.Values {
+	v := url.Values{}
+	for key, val := range map[string]string{
+		"utm_source":   u.Source,
+		"utm_medium":   u.Medium,
+		"utm_campaign": u.Campaign,
+		"utm_term":     u.Term,
+		"utm_content":  u.Content,
+	} {
+		if val != "" {
+			v.Set(key, val)
+		}
+	}
+	return v
+}
+
+// ParseUTM restore UTM from query string stored in repository
+func ParseUTM(s string) (*UTM, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := url.ParseQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	return &UTM{
+		Source:   v.Get("utm_source"),
+		Medium:   v.Get("utm_medium"),
+		Campaign: v.Get("utm_campaign"),
+		Term:     v.Get("utm_term"),
+		Content:  v.Get("utm_content"),
+	}, nil
+}
+
+// Platforms of rule
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformDesktop = "desktop"
+)
+
+// Rule structure for conditional destination of link, all set conditions must match
+type Rule struct {
+	Platform string `json:"platform,omitempty"`
+	Language string `json:"language,omitempty"`
+	Country  string `json:"country,omitempty"`
+	URL      string `json:"url"`
+}
+
+// LinkOptions structure for per-link redirect settings
+type LinkOptions struct {
+	RedirectCode     int        `json:"redirect_code,omitempty"`
+	QueryPassthrough bool       `json:"query_passthrough,omitempty"`
+	UTM              *UTM       `json:"utm,omitempty"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	Interstitial     bool       `json:"interstitial,omitempty"`
+
+	// Rules choose destination by client, first matching rule wins, long URL is the fallback
+	Rules []Rule `json:"rules,omitempty"`
+
+	// MaxClicks redirects before link is exhausted, 1 makes one-time link, 0 is unlimited
+	MaxClicks int `json:"max_clicks,omitempty"`
+
+	// Password in request, only its hash is stored
+	Password string `json:"password,omitempty"`
+}
+
+// Link structure for short link stored in repository
+type Link struct {
+	Short     string
+	Long      string
+	Canonical string
+	UserID    string
+	Deleted   bool
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+	DeletedAt *time.Time
+	Clicks    int64
+
+	// set by administrator, disabled link is answered with 451
+	DisabledAt     *time.Time
+	DisabledReason string
+
+	// bcrypt hash of link password, empty if link is not protected
+	PasswordHash string
+
+	// redirects left before link is exhausted, nil if clicks are not limited
+	ClicksLeft *int64
+
+	LinkOptions
+	LinkMeta
+}
+
+// LinkMeta structure for optional description of link set at creation
+type LinkMeta struct {
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// OptionalTime structure for JSON field that may be absent, null or set
+type OptionalTime struct {
+	Set  bool
+	Time *time.Time
+}
+
+// UnmarshalJSON mark field as set, null clears the value
+func (t *OptionalTime) UnmarshalJSON(b []byte) error {
+	t.Set = true
+	return json.Unmarshal(b, &t.Time)
+}
+
+// URLUpdateRequest structure for func UpdateUserURL, absent fields are not changed
+type URLUpdateRequest struct {
+	URL              *string      `json:"original_url"`
+	ExpiresAt        OptionalTime `json:"expires_at"`
+	RedirectCode     *int         `json:"redirect_code"`
+	QueryPassthrough *bool        `json:"query_passthrough"`
+	UTM              *UTM         `json:"utm"`
+	Tags             *[]string    `json:"tags"`
+	Interstitial     *bool        `json:"interstitial"`
+	Password         *string      `json:"password"`
+	MaxClicks        *int         `json:"max_clicks"`
+	Rules            *[]Rule      `json:"rules"`
+
+	// Canonical form of URL, set by handler when URL is changed
+	Canonical string `json:"-"`
+	// PasswordHash of new password, set by handler when password is changed
+	PasswordHash string `json:"-"`
+}
+
+// Apply change link fields that are set in update request
+func (u URLUpdateRequest) Apply(link *Link) {
+	if u.URL != nil {
+		link.Long = *u.URL
+		link.Canonical = u.Canonical
+	}
+	if u.ExpiresAt.Set {
+		link.ExpiresAt = u.ExpiresAt.Time
+	}
+	if u.RedirectCode != nil {
+		link.RedirectCode = *u.RedirectCode
+	}
+	if u.QueryPassthrough != nil {
+		link.QueryPassthrough = *u.QueryPassthrough
+	}
+	if u.UTM != nil {
+		link.UTM = u.UTM
+		if len(u.UTM.Values()) == 0 {
+			link.UTM = nil
+		}
+	}
+	if u.Tags != nil {
+		link.Tags = *u.Tags
+	}
+	if u.Interstitial != nil {
+		link.Interstitial = *u.Interstitial
+	}
+	if u.Password != nil {
+		link.PasswordHash = u.PasswordHash
+	}
+	if u.MaxClicks != nil {
+		link.ClicksLeft = ClicksLeft(*u.MaxClicks)
+	}
+	if u.Rules != nil {
+		link.Rules = *u.Rules
+	}
+}
+
+// ClicksLeft return counter of link with click limit, nil for unlimited link
+func ClicksLeft(max int) *int64 {
+	if max <= 0 {
+		return nil
+	}
+	n := int64(max)
+	return &n
+}
+
 // URLRequest structure for func PostJSONHandler
 type URLRequest struct {
 	URL string `json:"url"`
+	LinkOptions
+	LinkMeta
 }
 
 // URLResponse structure for func PostJSONHandler
 type URLResponse struct {
 	Result string `json:"result"`
+	QRURL  string `json:"qr_url,omitempty"`
+}
+
+// Sorting and page size of user links list
+const (
+	SortCreatedAt = "created_at"
+	SortClicks    = "clicks"
+
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// ListQuery structure for paged query of user links
+type ListQuery struct {
+	UserID  string
+	Limit   int
+	Cursor  string
+	Sort    string
+	Desc    bool
+	Deleted *bool
+	Expired *bool
+	Domain  string
+	Search  string
+	Tag     string
 }
 
 // URLsJSONResponse structure for func GetAllUserURLs
 type URLsJSONResponse struct {
-	Short string `json:"short_url"`
-	Long  string `json:"original_url"`
+	Short      string     `json:"short_url"`
+	Long       string     `json:"original_url"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	ClicksLeft *int64     `json:"clicks_left,omitempty"`
+	LinkMeta
+}
+
+// ExportRecord structure for func ExportUserURLs
+type ExportRecord struct {
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Deleted     bool       `json:"deleted"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Clicks      int64      `json:"clicks"`
 }
 
 // BatchRequest structure for func PostBatchHandler
-type BatchRequest []struct {
+type BatchRequest []BatchItem
+
+// BatchItem one URL of BatchRequest
+type BatchItem struct {
 	CorrelationID string `json:"correlation_id"`
 	OriginalURL   string `json:"original_url"`
+	LinkOptions
+	LinkMeta
 }
 
 // BatchResponse structure for func PostBatchHandler
 type BatchResponse struct {
 	CorrelationID string `json:"correlation_id"`
-	ShortURL      string `json:"short_url"`
+	ShortURL      string `json:"short_url,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+	QRURL         string `json:"qr_url,omitempty"`
+
+	// Err of item, transport sets Error from it
+	Err error `json:"-"`
+}
+
+// Statuses of delete job and of every ID in it
+const (
+	JobPending = "pending"
+	JobDone    = "done"
+	JobFailed  = "failed"
+
+	DeleteDeleted  = "deleted"
+	DeleteNotFound = "not_found"
+)
+
+// DeleteResult structure for status of one ID in DeleteJob
+type DeleteResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// DeleteJob structure for func GetDeleteJob
+type DeleteJob struct {
+	ID      string         `json:"job_id"`
+	UserID  string         `json:"-"`
+	Status  string         `json:"status"`
+	Results []DeleteResult `json:"results"`
+}
+
+// DeleteJobResponse structure for func DeleteUserURLs
+type DeleteJobResponse struct {
+	JobID string `json:"job_id"`
+}
+
+// Statuses of links added in batch
+const (
+	StatusCreated  = "created"
+	StatusExisting = "existing"
+	StatusError    = "error"
+)
+
+// AddResult structure for result of one link in Storage.AddBatch
+type AddResult struct {
+	Short  string
+	Status string
+	Err    error
+}
+
+// ImportRecord structure for one line of CSV or JSONL import
+type ImportRecord struct {
+	URL       string     `json:"url"`
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// ImportResult structure for result of one imported line
+type ImportResult struct {
+	Line     int    `json:"line"`
+	ShortURL string `json:"short_url,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+
+	// Err of line, transport sets Error from it
+	Err error `json:"-"`
 }
 
 // UserRequest structure for func DeleteUserUrls
@@ -48,3 +395,82 @@ type UserRequest struct {
 	UserID   string
 	UserUrls []string
 }
+
+// Stats structure for func GetStats
+type Stats struct {
+	URLs       int64 `json:"urls"`
+	Users      int64 `json:"users"`
+	Deleted    int64 `json:"deleted"`
+	CreatedDay int64 `json:"created_24h"`
+}
+
+// Actions written to audit log
+const (
+	AuditDisableLink = "disable_link"
+	AuditEnableLink  = "enable_link"
+	AuditBanUser     = "ban_user"
+	AuditUnbanUser   = "unban_user"
+	AuditSearchLinks = "search_links"
+	AuditClearCache  = "clear_cache"
+)
+
+// AuditEntry structure for one admin action
+type AuditEntry struct {
+	Actor  string
+	Action string
+	Target string
+	Detail string
+}
+
+// DisableRequest structure for func DisableURL
+type DisableRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ClearCacheResponse structure for func ClearCache
+type ClearCacheResponse struct {
+	QRCodes int `json:"qr_codes"`
+}
+
+// AdminURLResponse structure for func AdminSearchURLs
+type AdminURLResponse struct {
+	Short          string     `json:"short_url"`
+	Long           string     `json:"original_url"`
+	UserID         string     `json:"user_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Clicks         int64      `json:"clicks"`
+	Deleted        bool       `json:"deleted"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason string     `json:"disabled_reason,omitempty"`
+}
+
+// Preview structure for redirect preview page
+type Preview struct {
+	Short        string
+	Long         string
+	Host         string
+	CreatedAt    time.Time
+	ExpiresAt    *time.Time
+	Title        string
+	Description  string
+	Interstitial bool
+	Warnings     []string
+
+	// Token proves verified password of protected link on continue link
+	Token string
+}
+
+// Visit structure for request to follow short link
+type Visit struct {
+	Query     url.Values
+	Confirmed bool
+	Password  *string
+	IP        string
+
+	// Token from preview page of protected link, used instead of password until it expires
+	Token string
+
+	// headers matched by link rules
+	UserAgent      string
+	AcceptLanguage string
+}
@@ -0,0 +1,153 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/validation"
+)
+
+// ContentType of error responses, RFC 7807
+const ContentType = "application/problem+json"
+
+// Stable error codes returned to clients
+const (
+	CodeInvalidJSON    = "invalid_json"
+	CodeInvalidBody    = "invalid_body"
+	CodeBodyTooLarge   = "body_too_large"
+	CodeInvalidURL     = "invalid_url"
+	CodeInvalidOptions = "invalid_options"
+	CodeNotFound       = "not_found"
+	CodeGone           = "gone"
+	CodeConflict       = "conflict"
+	CodeAliasTaken     = "alias_taken"
+	CodeBatchTooLarge  = "batch_too_large"
+	CodeForbidden      = "forbidden"
+	CodeUnauthorized   = "unauthorized"
+	CodeDisabled       = "disabled"
+	CodePassword       = "password_required"
+	CodeWrongPassword  = "wrong_password"
+	CodeTooMany        = "too_many_attempts"
+	CodeInternal       = "internal"
+)
+
+// Problem structure for application/problem+json response
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Error structure for error with status and code known at the place where it happens
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// InvalidJSON wrap error of request body unmarshal
+func InvalidJSON(err error) error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeInvalidJSON, Detail: "request body is not valid JSON", Err: err}
+}
+
+// InvalidBody wrap error of request body read
+func InvalidBody(err error) error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeInvalidBody, Detail: "request body can not be read", Err: err}
+}
+
+// BodyTooLarge wrap error of request body read over size limit
+func BodyTooLarge(err error) error {
+	return &Error{Status: http.StatusRequestEntityTooLarge, Code: CodeBodyTooLarge, Detail: "request body is too large", Err: err}
+}
+
+// Forbidden error for client not allowed to resource
+func Forbidden(detail string) error {
+	return &Error{Status: http.StatusForbidden, Code: CodeForbidden, Detail: detail}
+}
+
+// Unauthorized error for request without valid credentials
+func Unauthorized(detail string) error {
+	return &Error{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Detail: detail}
+}
+
+// From map error to problem, unknown errors become 500 without details
+func From(err error) Problem {
+
+	p := Problem{Status: http.StatusInternalServerError, Code: CodeInternal}
+
+	var perr *Error
+	var verr *validation.Error
+	switch {
+	case errors.As(err, &perr):
+		p.Status, p.Code, p.Detail = perr.Status, perr.Code, perr.Detail
+	case errors.As(err, &verr):
+		p.Status, p.Code, p.Detail, p.Reason = http.StatusBadRequest, CodeInvalidURL, verr.Message, verr.Reason
+	case errors.Is(err, model.ErrInvalidURL):
+		p.Status, p.Code, p.Detail = http.StatusBadRequest, CodeInvalidURL, model.ErrInvalidURL.Error()
+	case errors.Is(err, model.ErrImportLine):
+		p.Status, p.Code, p.Detail = http.StatusBadRequest, CodeInvalidBody, err.Error()
+	case errors.Is(err, model.ErrBatchTooLarge):
+		p.Status, p.Code, p.Detail = http.StatusRequestEntityTooLarge, CodeBatchTooLarge, err.Error()
+	case errors.Is(err, model.ErrNotFound):
+		p.Status, p.Code, p.Detail = http.StatusNotFound, CodeNotFound, err.Error()
+	case errors.Is(err, model.ErrDisabled):
+		p.Status, p.Code, p.Detail = http.StatusUnavailableForLegalReasons, CodeDisabled, err.Error()
+	case errors.Is(err, model.ErrPasswordRequired):
+		p.Status, p.Code, p.Detail = http.StatusUnauthorized, CodePassword, err.Error()
+	case errors.Is(err, model.ErrPasswordWrong):
+		p.Status, p.Code, p.Detail = http.StatusForbidden, CodeWrongPassword, err.Error()
+	case errors.Is(err, model.ErrTooManyAttempts):
+		p.Status, p.Code, p.Detail = http.StatusTooManyRequests, CodeTooMany, err.Error()
+	case errors.Is(err, model.ErrBanned):
+		p.Status, p.Code, p.Detail = http.StatusForbidden, CodeForbidden, err.Error()
+	case errors.Is(err, model.ErrDelFlag), errors.Is(err, model.ErrExpired), errors.Is(err, model.ErrExhausted):
+		p.Status, p.Code, p.Detail = http.StatusGone, CodeGone, err.Error()
+	case errors.Is(err, model.ErrConflict):
+		p.Status, p.Code, p.Detail = http.StatusConflict, CodeConflict, err.Error()
+	case errors.Is(err, model.ErrAliasTaken):
+		p.Status, p.Code, p.Detail = http.StatusConflict, CodeAliasTaken, err.Error()
+	case errors.Is(err, model.ErrRedirectCode), errors.Is(err, model.ErrExpiresAt), errors.Is(err, model.ErrAlias),
+		errors.Is(err, model.ErrCursor), errors.Is(err, model.ErrTitle), errors.Is(err, model.ErrDescription),
+		errors.Is(err, model.ErrTags), errors.Is(err, model.ErrPassword),
+		errors.Is(err, model.ErrMaxClicks), errors.Is(err, model.ErrListQuery), errors.Is(err, model.ErrRules):
+		p.Status, p.Code, p.Detail = http.StatusBadRequest, CodeInvalidOptions, err.Error()
+	}
+
+	p.Type = "about:blank"
+	p.Title = http.StatusText(p.Status)
+
+	return p
+}
+
+// Write write error as application/problem+json response
+func Write(w http.ResponseWriter, r *http.Request, err error) Problem {
+
+	p := From(err)
+	p.Instance = r.URL.Path
+
+	j, _ := json.Marshal(&p)
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_, _ = w.Write(j)
+
+	return p
+}
@@ -0,0 +1,43 @@
+package purge
+
+import (
+	"context"
+	"time"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/repository"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+)
+
+// Run remove URLs deleted and delete jobs finished longer than retention window ago every PurgeInterval until ctx is done
+func Run(ctx context.Context, rep repository.Pool, cfg config.Config, logger logging.Logger) {
+
+	if cfg.PurgeInterval <= 0 {
+		logger.Info("purge of deleted urls is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c, cancel := context.WithTimeout(ctx, model.TimeOut)
+			n, err := rep.Storage.Purge(c, cfg.DeleteRetention)
+			if err != nil {
+				logger.Error(err)
+			} else if n > 0 {
+				logger.Infof("purged %d deleted urls", n)
+			}
+			_, err = rep.Jobs.Purge(c, cfg.DeleteRetention)
+			if err != nil {
+				logger.Error(err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// Package qr render QR codes of short URLs as PNG or SVG and cache them
+package qr
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Formats of QR code
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// Limits of QR code parameters
+const (
+	DefaultSize   = 256
+	MinSize       = 64
+	MaxSize       = 2048
+	DefaultMargin = 4
+	MaxMargin     = 16
+)
+
+// ErrOptions returned for invalid format, size, level or margin
+var ErrOptions = errors.New("qr: format must be png or svg, size 64-2048, level L, M, Q or H, margin 0-16")
+
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Options of QR code image, size is in pixels and margin in modules
+type Options struct {
+	Format string
+	Size   int
+	Level  string
+	Margin int
+}
+
+// ParseOptions parse format, size, level and margin query parameters
+func ParseOptions(v url.Values) (Options, error) {
+
+	o := Options{Format: FormatPNG, Size: DefaultSize, Level: "M", Margin: DefaultMargin}
+
+	if s := v.Get("format"); s != "" {
+		o.Format = strings.ToLower(s)
+	}
+	if o.Format != FormatPNG && o.Format != FormatSVG {
+		return o, ErrOptions
+	}
+
+	if s := v.Get("size"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < MinSize || n > MaxSize {
+			return o, ErrOptions
+		}
+		o.Size = n
+	}
+
+	if s := v.Get("level"); s != "" {
+		o.Level = strings.ToUpper(s)
+	}
+	if _, ok := levels[o.Level]; !ok {
+		return o, ErrOptions
+	}
+
+	if s := v.Get("margin"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > MaxMargin {
+			return o, ErrOptions
+		}
+		o.Margin = n
+	}
+
+	return o, nil
+}
+
+// ContentType of image in format
+func (o Options) ContentType() string {
+	if o.Format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Encode render QR code of content
+func Encode(content string, o Options) ([]byte, error) {
+
+	q, err := qrcode.New(content, levels[o.Level])
+	if err != nil {
+		return nil, err
+	}
+	q.DisableBorder = true
+	bitmap := q.Bitmap()
+
+	if o.Format == FormatSVG {
+		return encodeSVG(bitmap, o), nil
+	}
+	return encodePNG(bitmap, o)
+}
+
+// encodePNG scale modules to fit size, image is Size pixels wide unless it is less than one pixel per module
+func encodePNG(bitmap [][]bool, o Options) ([]byte, error) {
+
+	modules := len(bitmap) + 2*o.Margin
+	scale := o.Size / modules
+	if scale < 1 {
+		scale = 1
+	}
+	side := modules * scale
+	offset := (o.Size - side) / 2
+	if offset < 0 {
+		offset = 0
+	}
+	width := side + 2*offset
+
+	img := image.NewPaletted(image.Rect(0, 0, width, width), color.Palette{color.White, color.Black})
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			x0 := offset + (x+o.Margin)*scale
+			y0 := offset + (y+o.Margin)*scale
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(x0+dx, y0+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeSVG draw dark modules as one path in viewBox of modules
+func encodeSVG(bitmap [][]bool, o Options) []byte {
+
+	modules := len(bitmap) + 2*o.Margin
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		o.Size, o.Size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, modules, modules)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+o.Margin, y+o.Margin)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes()
+}
+
+// Cache of rendered QR codes with least recently used eviction
+type Cache struct {
+	mu    sync.Mutex
+	max   int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type entry struct {
+	key  string
+	data []byte
+}
+
+// NewCache create cache of max images, cache is disabled if max <= 0
+func NewCache(max int) *Cache {
+	return &Cache{max: max, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Reset drop all cached images and return their count
+func (c *Cache) Reset() int {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.ll.Len()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+
+	return n
+}
+
+// Get return QR code of content from cache or render and cache it
+func (c *Cache) Get(content string, o Options) ([]byte, error) {
+
+	if c.max <= 0 {
+		return Encode(content, o)
+	}
+
+	key := fmt.Sprintf("%s|%d|%s|%d|%s", o.Format, o.Size, o.Level, o.Margin, content)
+
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		data := el.Value.(*entry).data
+		c.mu.Unlock()
+		return data, nil
+	}
+	c.mu.Unlock()
+
+	data, err := Encode(content, o)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[key]; !ok {
+		c.items[key] = c.ll.PushFront(&entry{key: key, data: data})
+		for c.ll.Len() > c.max {
+			el := c.ll.Back()
+			c.ll.Remove(el)
+			delete(c.items, el.Value.(*entry).key)
+		}
+	}
+
+	return data, nil
+}
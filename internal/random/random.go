@@ -0,0 +1,18 @@
+// Package random generate random strings for short URLs and IDs
+package random
+
+import "math/rand"
+
+// letterBytes characters of generated strings
+const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890"
+
+// String generate string of n letters and digits
+func String(n int) string {
+
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letterBytes[rand.Intn(len(letterBytes))]
+	}
+
+	return string(b)
+}
@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/conn"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(cfg config.Config) (*Repository, error) {
+
+	pool := conn.NewConnection(cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), model.TimeOut)
+	defer cancel()
+
+	if _, err := pool.Exec(ctx, `
+	create table if not exists audit_log (
+	    id bigserial primary key,
+	    created_at timestamptz not null default now(),
+	    actor text,
+	    action varchar(32),
+	    target text,
+	    detail text
+	);
+	create index if not exists audit_log_target_idx on audit_log (target, created_at)
+
+`); err != nil {
+		return nil, err
+	}
+
+	return &Repository{
+		pool: pool,
+	}, nil
+}
+
+func (p *Repository) Add(ctx context.Context, entry model.AuditEntry) error {
+
+	if _, err := p.pool.Exec(ctx, `insert into audit_log (actor, action, target, detail) values ($1, $2, $3, $4)`,
+		entry.Actor, entry.Action, entry.Target, entry.Detail); err != nil {
+		return err
+	}
+
+	return nil
+}
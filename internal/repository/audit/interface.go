@@ -0,0 +1,11 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+type Audit interface {
+	Add(ctx context.Context, entry model.AuditEntry) error
+}
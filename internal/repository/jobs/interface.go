@@ -0,0 +1,15 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+type Jobs interface {
+	Create(ctx context.Context, job model.DeleteJob) error
+	Finish(ctx context.Context, job model.DeleteJob) error
+	Get(ctx context.Context, id, userID string) (model.DeleteJob, error)
+	Purge(ctx context.Context, retention time.Duration) (int64, error)
+}
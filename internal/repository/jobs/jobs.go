@@ -0,0 +1,87 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/conn"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(cfg config.Config) (*Repository, error) {
+
+	pool := conn.NewConnection(cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), model.TimeOut)
+	defer cancel()
+
+	if _, err := pool.Exec(ctx, `
+	create table if not exists delete_jobs (
+	    job_id varchar(16) primary key,
+	    user_id varchar(16),
+	    status varchar(16),
+	    results jsonb,
+	    created_at timestamptz not null default now(),
+	    finished_at timestamptz
+	)
+
+`); err != nil {
+		return nil, err
+	}
+
+	return &Repository{
+		pool: pool,
+	}, nil
+}
+
+func (p *Repository) Create(ctx context.Context, job model.DeleteJob) error {
+
+	if _, err := p.pool.Exec(ctx, `insert into delete_jobs (job_id, user_id, status, results) values ($1, $2, $3, $4)`,
+		job.ID, job.UserID, job.Status, job.Results); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *Repository) Finish(ctx context.Context, job model.DeleteJob) error {
+
+	if _, err := p.pool.Exec(ctx, `update delete_jobs set status = $1, results = $2, finished_at = now() where job_id = $3`,
+		job.Status, job.Results, job.ID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *Repository) Get(ctx context.Context, id, userID string) (model.DeleteJob, error) {
+
+	job := model.DeleteJob{ID: id, UserID: userID}
+	row := p.pool.QueryRow(ctx, `select status, results from delete_jobs where job_id = $1 and user_id = $2`, id, userID)
+	if err := row.Scan(&job.Status, &job.Results); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.DeleteJob{}, model.ErrNotFound
+		}
+		return model.DeleteJob{}, err
+	}
+
+	return job, nil
+}
+
+// Purge remove jobs finished more than retention ago
+func (p *Repository) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+
+	tag, err := p.pool.Exec(ctx, `delete from delete_jobs where finished_at < $1`, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/repository/audit"
+	"github.com/RomanIkonnikov93/URLshortner/internal/repository/jobs"
 	"github.com/RomanIkonnikov93/URLshortner/internal/repository/storage"
 	"github.com/RomanIkonnikov93/URLshortner/internal/repository/users"
 )
@@ -10,6 +12,8 @@ type Pool struct {
 	Users   *users.Repository
 	Storage *storage.Repository
 	Ping    *Ping
+	Jobs    *jobs.Repository
+	Audit   *audit.Repository
 }
 
 func NewReps(cfg config.Config) (*Pool, error) {
@@ -29,9 +33,21 @@ func NewReps(cfg config.Config) (*Pool, error) {
 		return nil, err
 	}
 
+	j, err := jobs.NewRepository(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := audit.NewRepository(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Pool{
 		Users:   u,
 		Storage: s,
 		Ping:    p,
+		Jobs:    j,
+		Audit:   a,
 	}, nil
 }
@@ -2,14 +2,24 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/RomanIkonnikov93/URLshortner/internal/model"
 )
 
 type Storage interface {
-	Add(ctx context.Context, short, long, id string) error
-	Get(ctx context.Context, short string) (string, error)
-	GetByUserID(ctx context.Context, id string) (map[string]string, error)
-	GetShort(ctx context.Context, long string) (string, error)
-	BatchDelete(batch model.UserRequest) error
+	Add(ctx context.Context, link model.Link) error
+	AddBatch(ctx context.Context, links []model.Link) ([]model.AddResult, error)
+	Get(ctx context.Context, short string) (model.Link, error)
+	GetPage(ctx context.Context, q model.ListQuery) ([]model.Link, string, error)
+	GetShort(ctx context.Context, canonical, id string) (string, error)
+	Update(ctx context.Context, short, id string, upd model.URLUpdateRequest) (model.Link, error)
+	BatchDelete(batch model.UserRequest) ([]string, error)
+	Restore(ctx context.Context, batch model.UserRequest, retention time.Duration) ([]string, error)
+	Purge(ctx context.Context, retention time.Duration) (int64, error)
+	Click(ctx context.Context, short string) error
+	Export(ctx context.Context, id string, fn func(link model.Link) error) error
+	ShortsByTag(ctx context.Context, id, tag string) ([]string, error)
+	Stats(ctx context.Context) (model.Stats, error)
+	SetDisabled(ctx context.Context, short string, disabled bool, reason string) error
 }
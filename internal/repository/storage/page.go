@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+// hostExpr extract host from canonical URL
+const hostExpr = `substring(canonical from '^[a-z][a-z0-9+.-]*://(?:[^@/]*@)?([^/:?#]+)')`
+
+// GetPage return one page of user links filtered and sorted by query and cursor of the next page,
+// cursor is empty on the last page, links of all users are returned if query has no UserID
+func (p *Repository) GetPage(ctx context.Context, q model.ListQuery) ([]model.Link, string, error) {
+
+	sortCol := "created_at"
+	if q.Sort == model.SortClicks {
+		sortCol = "clicks"
+	}
+	cmp, dir := ">", "asc"
+	if q.Desc {
+		cmp, dir = "<", "desc"
+	}
+
+	args := []interface{}{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	query := `select ` + metaColumns + ` from urls where true`
+	if q.UserID != "" {
+		query += ` and user_id = ` + arg(q.UserID)
+	}
+	if q.Deleted != nil {
+		query += ` and del_flag = ` + arg(*q.Deleted)
+	}
+	if q.Expired != nil {
+		if *q.Expired {
+			query += ` and expires_at <= now()`
+		} else {
+			query += ` and (expires_at is null or expires_at > now())`
+		}
+	}
+	if q.Domain != "" {
+		d := arg(q.Domain)
+		query += ` and (` + hostExpr + ` = ` + d + ` or ` + hostExpr + ` like '%.' || ` + d + `)`
+	}
+	if q.Search != "" {
+		query += ` and long ilike ` + arg("%"+likeEscape(q.Search)+"%")
+	}
+	if q.Tag != "" {
+		query += ` and exists (select 1 from link_tags t where t.short = urls.short and t.tag = ` + arg(q.Tag) + `)`
+	}
+	if q.Cursor != "" {
+		value, short, err := decodeCursor(q.Cursor, q.Sort)
+		if err != nil {
+			return nil, "", err
+		}
+		query += ` and (` + sortCol + `, short) ` + cmp + ` (` + arg(value) + `, ` + arg(short) + `)`
+	}
+	query += ` order by ` + sortCol + ` ` + dir + `, short ` + dir + ` limit ` + arg(q.Limit+1)
+
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	out := make([]model.Link, 0, q.Limit)
+	for rows.Next() {
+		link := model.Link{}
+		if err := rows.Scan(metaDest(&link)...); err != nil {
+			return nil, "", err
+		}
+		out = append(out, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(out) > q.Limit {
+		out = out[:q.Limit]
+		next = encodeCursor(out[len(out)-1], q.Sort)
+	}
+
+	return out, next, nil
+}
+
+// likeEscape escape special characters of like pattern
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// encodeCursor make cursor from sort value and short URL of the last link on page
+func encodeCursor(link model.Link, sort string) string {
+	value := link.CreatedAt.UTC().Format(time.RFC3339Nano)
+	if sort == model.SortClicks {
+		value = strconv.FormatInt(link.Clicks, 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(sort + "|" + value + "|" + link.Short))
+}
+
+func decodeCursor(cursor, sort string) (interface{}, string, error) {
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, "", model.ErrCursor
+	}
+	parts := strings.SplitN(string(b), "|", 3)
+	if len(parts) != 3 || parts[0] != sort {
+		return nil, "", model.ErrCursor
+	}
+
+	if sort == model.SortClicks {
+		n, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, "", model.ErrCursor
+		}
+		return n, parts[2], nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return nil, "", model.ErrCursor
+	}
+	return t, parts[2], nil
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+
+	created := time.Date(2024, 3, 1, 12, 30, 45, 123456789, time.FixedZone("X", 3*3600))
+	link := model.Link{Short: "a|b", CreatedAt: created, Clicks: 42}
+
+	tests := []struct {
+		sort  string
+		value interface{}
+	}{
+		{model.SortCreatedAt, created.UTC()},
+		{model.SortClicks, int64(42)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			value, short, err := decodeCursor(encodeCursor(link, tt.sort), tt.sort)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if short != link.Short {
+				t.Fatalf("short = %q, want %q", short, link.Short)
+			}
+			if tm, ok := value.(time.Time); ok {
+				if !tm.Equal(tt.value.(time.Time)) {
+					t.Fatalf("value = %v, want %v", tm, tt.value)
+				}
+				return
+			}
+			if value != tt.value {
+				t.Fatalf("value = %v, want %v", value, tt.value)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+
+	enc := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	link := model.Link{Short: "abc", CreatedAt: time.Now(), Clicks: 1}
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   string
+	}{
+		{"not base64", "!!!", model.SortCreatedAt},
+		{"empty", "", model.SortCreatedAt},
+		{"too few parts", enc("clicks|1"), model.SortClicks},
+		{"other sort", encodeCursor(link, model.SortClicks), model.SortCreatedAt},
+		{"bad clicks", enc("clicks|x|abc"), model.SortClicks},
+		{"bad time", enc("created_at|yesterday|abc"), model.SortCreatedAt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCursor(tt.cursor, tt.sort); !errors.Is(err, model.ErrCursor) {
+				t.Fatalf("decodeCursor(%q) error = %v, want %v", tt.cursor, err, model.ErrCursor)
+			}
+		})
+	}
+}
@@ -2,17 +2,23 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
 	"github.com/RomanIkonnikov93/URLshortner/internal/conn"
 	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/urlnorm"
 	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 type Repository struct {
-	pool *pgxpool.Pool
+	pool  *pgxpool.Pool
+	dedup string
 }
 
 func NewRepository(cfg config.Config) (*Repository, error) {
@@ -24,29 +30,192 @@ func NewRepository(cfg config.Config) (*Repository, error) {
 	if _, err := pool.Exec(ctx, `
 	create table if not exists urls (
 	    short varchar(5),
-	    long text unique,
+	    long text,
 	    user_id varchar(16),
 	    del_flag boolean
-	)
+	);
+	alter table urls add column if not exists redirect_code integer not null default 307;
+	alter table urls add column if not exists query_passthrough boolean not null default false;
+	alter table urls add column if not exists utm text not null default '';
+	alter table urls add column if not exists expires_at timestamptz;
+	alter table urls add column if not exists deleted_at timestamptz;
+	update urls set deleted_at = now() where del_flag and deleted_at is null;
+	alter table urls add column if not exists canonical text;
+	alter table urls drop constraint if exists urls_long_key;
+	alter table urls alter column short type varchar(32);
+	-- short URLs were not unique before, duplicates except the first one get suffix before unique index is created
+	do $$
+	begin
+	    if to_regclass('urls_short_key') is null then
+	        update urls u set short = d.short || '-dup' || d.n
+	        from (select ctid, short, row_number() over (partition by short order by ctid) - 1 as n from urls) d
+	        where u.ctid = d.ctid and d.n > 0;
+	    end if;
+	end $$;
+	create unique index if not exists urls_short_key on urls (short);
+	alter table urls add column if not exists created_at timestamptz not null default now();
+	alter table urls add column if not exists clicks bigint not null default 0;
+	create index if not exists urls_user_created_idx on urls (user_id, created_at, short);
+	alter table urls add column if not exists updated_at timestamptz;
+	alter table urls add column if not exists title text not null default '';
+	alter table urls add column if not exists description text not null default '';
+	alter table urls add column if not exists disabled_at timestamptz;
+	alter table urls add column if not exists disabled_reason text not null default '';
+	alter table urls add column if not exists interstitial boolean not null default false;
+	alter table urls add column if not exists password_hash text not null default '';
+	alter table urls add column if not exists clicks_left bigint;
+	alter table urls add column if not exists rules jsonb not null default '[]';
+	create table if not exists link_tags (
+	    short varchar(32),
+	    tag varchar(64),
+	    primary key (short, tag)
+	);
+	create index if not exists link_tags_tag_idx on link_tags (tag);
+	create table if not exists url_revisions (
+	    short varchar(32),
+	    long text,
+	    redirect_code integer,
+	    query_passthrough boolean,
+	    utm text,
+	    expires_at timestamptz,
+	    changed_at timestamptz not null default now()
+	);
+	alter table url_revisions alter column short type varchar(32);
+	alter table url_revisions add column if not exists interstitial boolean not null default false;
+	alter table url_revisions add column if not exists rules jsonb not null default '[]'
 
 `); err != nil {
 		return nil, err
 	}
 
+	if err := backfillCanonical(pool, cfg); err != nil {
+		return nil, err
+	}
+
+	// unique index that defines how long URLs are deduplicated, deleted links don't take part in it,
+	// indexes of previous versions covered deleted links too and are dropped
+	var dedup string
+	switch cfg.DedupMode {
+	case model.DedupGlobal:
+		dedup = `
+	drop index if exists urls_canonical_key;
+	drop index if exists urls_user_canonical_key;
+	drop index if exists urls_user_canonical_live_key;
+	create unique index if not exists urls_canonical_live_key on urls (canonical) where not del_flag`
+	case model.DedupUser:
+		dedup = `
+	drop index if exists urls_canonical_key;
+	drop index if exists urls_user_canonical_key;
+	drop index if exists urls_canonical_live_key;
+	create unique index if not exists urls_user_canonical_live_key on urls (user_id, canonical) where not del_flag`
+	case model.DedupNone:
+		dedup = `
+	drop index if exists urls_canonical_key;
+	drop index if exists urls_user_canonical_key;
+	drop index if exists urls_canonical_live_key;
+	drop index if exists urls_user_canonical_live_key`
+	default:
+		return nil, fmt.Errorf("unknown dedup mode %q", cfg.DedupMode)
+	}
+	if _, err := pool.Exec(ctx, dedup); err != nil {
+		return nil, err
+	}
+
 	p := &Repository{
-		pool: pool,
+		pool:  pool,
+		dedup: cfg.DedupMode,
 	}
 
 	return p, nil
 }
 
-func (p *Repository) Add(ctx context.Context, short, long, id string) error {
+// canonicalBatch rows of URLs created before canonical column updated at once
+const canonicalBatch = 500
 
-	flag := false
-	if _, err := p.pool.Exec(ctx, `insert into urls (short, long, user_id, del_flag) values ($1, $2 ,$3,$4)`, short, long, id, flag); err != nil {
+// backfillCanonical set canonical form of long URLs created before canonical column,
+// a live link that would duplicate canonical form of another live link keeps its long URL as it is,
+// so that unique index of dedup mode can be created
+func backfillCanonical(pool *pgxpool.Pool, cfg config.Config) error {
+
+	dup := `false`
+	switch cfg.DedupMode {
+	case model.DedupGlobal:
+		dup = `exists (select 1 from urls l where l.canonical = $1 and not l.del_flag)`
+	case model.DedupUser:
+		dup = `exists (select 1 from urls l where l.canonical = $1 and l.user_id = urls.user_id and not l.del_flag)`
+	}
+	query := `update urls set canonical = case when not coalesce(del_flag, false) and ` + dup + ` then coalesce(long, '') else $1 end where short = $2`
+
+	for {
+		n, err := backfillCanonicalBatch(pool, cfg, query)
+		if err != nil {
+			return err
+		}
+		if n < canonicalBatch {
+			return nil
+		}
+	}
+}
+
+func backfillCanonicalBatch(pool *pgxpool.Pool, cfg config.Config, query string) (int, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), model.TimeOut)
+	defer cancel()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `select short, coalesce(long, '') from urls where canonical is null and short is not null limit $1 for update`, canonicalBatch)
+	if err != nil {
+		return 0, err
+	}
+	batch := &pgx.Batch{}
+	for rows.Next() {
+		var short, long string
+		if err := rows.Scan(&short, &long); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		canonical, err := urlnorm.Canonical(long, cfg.StripTrackingParams)
+		if err != nil {
+			canonical = long
+		}
+		batch.Queue(query, canonical, short)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return 0, err
+	}
+
+	return batch.Len(), tx.Commit(ctx)
+}
+
+func (p *Repository) Add(ctx context.Context, link model.Link) error {
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `insert into urls (`+insertColumns+`) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+		linkArgs(link)...); err != nil {
 		pgerr, ok := err.(*pgconn.PgError)
 		if ok {
 			if pgerr.Code == "23505" {
+				if pgerr.ConstraintName == "urls_short_key" {
+					return model.ErrAliasTaken
+				}
 				return model.ErrConflict
 			}
 		}
@@ -54,78 +223,487 @@ func (p *Repository) Add(ctx context.Context, short, long, id string) error {
 		return err
 	}
 
-	return nil
+	if err := insertTags(ctx, tx, []model.Link{link}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// insertColumns of urls in order of linkArgs
+const insertColumns = `short, long, canonical, user_id, del_flag, redirect_code, query_passthrough, utm, expires_at, title, description, interstitial, password_hash, clicks_left, rules`
+
+// linkArgs return insert arguments in order of insertColumns used by Add and AddBatch
+func linkArgs(link model.Link) []interface{} {
+
+	utm := ""
+	if link.UTM != nil {
+		utm = link.UTM.Values().Encode()
+	}
+
+	flag := false
+	return []interface{}{link.Short, link.Long, link.Canonical, link.UserID, flag, link.RedirectCode, link.QueryPassthrough, utm, link.ExpiresAt,
+		link.Title, link.Description, link.Interstitial, link.PasswordHash, model.ClicksLeft(link.MaxClicks), rulesArg(link.Rules)}
+}
+
+// rulesArg return link rules as jsonb argument, no rules are stored as empty array
+func rulesArg(rules []model.Rule) []model.Rule {
+	if rules == nil {
+		return []model.Rule{}
+	}
+	return rules
+}
+
+// insertTags add tags of links to link_tags
+func insertTags(ctx context.Context, tx pgx.Tx, links []model.Link) error {
+
+	shorts := make([]string, 0)
+	tags := make([]string, 0)
+	for _, link := range links {
+		for _, tag := range link.Tags {
+			shorts = append(shorts, link.Short)
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `insert into link_tags (short, tag) select * from unnest($1::varchar[], $2::varchar[]) on conflict do nothing`, shorts, tags)
+	return err
 }
 
-func (p *Repository) Get(ctx context.Context, short string) (string, error) {
+// batchChunk limit rows in one insert statement, postgres allows 65535 parameters
+const batchChunk = 1000
 
-	rows, err := p.pool.Query(ctx, `select long, del_flag from urls where short = $1`, short)
+// insertArgs count of insertColumns
+const insertArgs = 15
+
+// AddBatch insert links in one transaction and return status for each of them:
+// created, existing with short URL of the duplicate, or error when the short URL is taken
+func (p *Repository) AddBatch(ctx context.Context, links []model.Link) ([]model.AddResult, error) {
+
+	tx, err := p.pool.Begin(ctx)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	var out string
+	defer tx.Rollback(ctx)
+
+	// inserted short URL with canonical form of its long URL
+	inserted := make(map[string]string, len(links))
+	for start := 0; start < len(links); start += batchChunk {
+		end := start + batchChunk
+		if end > len(links) {
+			end = len(links)
+		}
+
+		query := `insert into urls (` + insertColumns + `) values `
+		args := make([]interface{}, 0, (end-start)*insertArgs)
+		for i, link := range links[start:end] {
+			if i > 0 {
+				query += `, `
+			}
+			query += `(`
+			for j := 1; j <= insertArgs; j++ {
+				if j > 1 {
+					query += `, `
+				}
+				query += `$` + strconv.Itoa(len(args)+j)
+			}
+			query += `)`
+			args = append(args, linkArgs(link)...)
+		}
+		query += ` on conflict do nothing returning short, canonical`
+
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var short, canonical string
+			if err := rows.Scan(&short, &canonical); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			inserted[short] = canonical
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// find duplicates of links that were not inserted
+	existing := make(map[string]string)
+	if p.dedup != model.DedupNone {
+		canonical := make([]string, 0)
+		for _, link := range links {
+			if inserted[link.Short] != link.Canonical {
+				canonical = append(canonical, link.Canonical)
+			}
+		}
+		if len(canonical) > 0 {
+			rows, err := tx.Query(ctx, `select short, canonical, user_id from urls where canonical = any($1) and not del_flag`, canonical)
+			if err != nil {
+				return nil, err
+			}
+			for rows.Next() {
+				var link model.Link
+				if err := rows.Scan(&link.Short, &link.Canonical, &link.UserID); err != nil {
+					rows.Close()
+					return nil, err
+				}
+				existing[p.dedupKey(link)] = link.Short
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	out := make([]model.AddResult, 0, len(links))
+	created := make([]model.Link, 0, len(links))
+	for _, link := range links {
+		if c, ok := inserted[link.Short]; ok && c == link.Canonical {
+			// the same short URL may be requested twice, only the first one is created
+			delete(inserted, link.Short)
+			created = append(created, link)
+			out = append(out, model.AddResult{Short: link.Short, Status: model.StatusCreated})
+			continue
+		}
+		if short, ok := existing[p.dedupKey(link)]; ok {
+			out = append(out, model.AddResult{Short: short, Status: model.StatusExisting})
+			continue
+		}
+		out = append(out, model.AddResult{Status: model.StatusError, Err: model.ErrAliasTaken})
+	}
+
+	if err := insertTags(ctx, tx, created); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// dedupKey return key by which links are considered duplicates in current dedup mode
+func (p *Repository) dedupKey(link model.Link) string {
+	if p.dedup == model.DedupUser {
+		return link.UserID + "/" + link.Canonical
+	}
+	return link.Canonical
+}
+
+func (p *Repository) Get(ctx context.Context, short string) (model.Link, error) {
+
+	row := p.pool.QueryRow(ctx, `select long, user_id, del_flag, redirect_code, query_passthrough, utm, expires_at, disabled_at,
+		interstitial, created_at, title, description, password_hash, clicks_left, rules from urls where short = $1`, short)
+
+	out := model.Link{Short: short}
 	var flag bool
-	for rows.Next() {
-		if err := rows.Scan(&out, &flag); err != nil {
-			return "", err
+	var utm string
+	if err := row.Scan(&out.Long, &out.UserID, &flag, &out.RedirectCode, &out.QueryPassthrough, &utm, &out.ExpiresAt, &out.DisabledAt,
+		&out.Interstitial, &out.CreatedAt, &out.Title, &out.Description, &out.PasswordHash, &out.ClicksLeft, &out.Rules); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Link{}, model.ErrNotFound
 		}
+		return model.Link{}, err
+	}
+	if out.DisabledAt != nil {
+		return model.Link{}, model.ErrDisabled
 	}
 	if flag {
-		return "", model.ErrDelFlag
+		return model.Link{}, model.ErrDelFlag
+	}
+	if out.ExpiresAt != nil && time.Now().After(*out.ExpiresAt) {
+		return model.Link{}, model.ErrExpired
+	}
+	if out.ClicksLeft != nil && *out.ClicksLeft <= 0 {
+		return model.Link{}, model.ErrExhausted
+	}
+	var err error
+	out.UTM, err = model.ParseUTM(utm)
+	if err != nil {
+		return model.Link{}, err
 	}
 	return out, nil
 }
 
-func (p *Repository) GetByUserID(ctx context.Context, id string) (map[string]string, error) {
+// Update change target and options of user link, previous values are saved to url_revisions
+func (p *Repository) Update(ctx context.Context, short, id string, upd model.URLUpdateRequest) (model.Link, error) {
 
-	rows, err := p.pool.Query(ctx, `select short, long from urls where user_id = $1`, id)
+	tx, err := p.pool.Begin(ctx)
 	if err != nil {
-		return nil, err
+		return model.Link{}, err
 	}
-	defer rows.Close()
+	defer tx.Rollback(ctx)
 
-	m := make(map[string]string)
+	link := model.Link{Short: short, UserID: id}
+	var utm string
+	row := tx.QueryRow(ctx, `select long, canonical, redirect_code, query_passthrough, utm, expires_at, interstitial, password_hash, clicks_left, rules from urls
+		where short = $1 and user_id = $2 and not del_flag for update`, short, id)
+	if err := row.Scan(&link.Long, &link.Canonical, &link.RedirectCode, &link.QueryPassthrough, &utm, &link.ExpiresAt, &link.Interstitial,
+		&link.PasswordHash, &link.ClicksLeft, &link.Rules); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Link{}, model.ErrNotFound
+		}
+		return model.Link{}, err
+	}
 
-	for rows.Next() {
-		var short, long string
-		if err := rows.Scan(&short, &long); err != nil {
-			return nil, err
+	if _, err := tx.Exec(ctx, `insert into url_revisions (short, long, redirect_code, query_passthrough, utm, expires_at, interstitial, rules)
+		values ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		short, link.Long, link.RedirectCode, link.QueryPassthrough, utm, link.ExpiresAt, link.Interstitial, rulesArg(link.Rules)); err != nil {
+		return model.Link{}, err
+	}
+
+	link.UTM, err = model.ParseUTM(utm)
+	if err != nil {
+		return model.Link{}, err
+	}
+	upd.Apply(&link)
+
+	utm = ""
+	if link.UTM != nil {
+		utm = link.UTM.Values().Encode()
+	}
+	row = tx.QueryRow(ctx, `update urls set long = $1, canonical = $2, redirect_code = $3, query_passthrough = $4, utm = $5, expires_at = $6,
+		interstitial = $7, password_hash = $8, clicks_left = $9, rules = $10, updated_at = now()
+		where short = $11 and user_id = $12 returning created_at, updated_at, title, description`,
+		link.Long, link.Canonical, link.RedirectCode, link.QueryPassthrough, utm, link.ExpiresAt, link.Interstitial, link.PasswordHash, link.ClicksLeft,
+		rulesArg(link.Rules), short, id)
+	if err := row.Scan(&link.CreatedAt, &link.UpdatedAt, &link.Title, &link.Description); err != nil {
+		pgerr, ok := err.(*pgconn.PgError)
+		if ok {
+			if pgerr.Code == "23505" {
+				return model.Link{}, model.ErrConflict
+			}
+		}
+		return model.Link{}, err
+	}
+
+	if upd.Tags != nil {
+		if _, err := tx.Exec(ctx, `delete from link_tags where short = $1`, short); err != nil {
+			return model.Link{}, err
+		}
+		if err := insertTags(ctx, tx, []model.Link{link}); err != nil {
+			return model.Link{}, err
+		}
+	} else {
+		if err := tx.QueryRow(ctx, `select array(select tag from link_tags where short = $1 order by tag)`, short).Scan(&link.Tags); err != nil {
+			return model.Link{}, err
 		}
-		m[short] = long
 	}
 
-	return m, nil
+	if err := tx.Commit(ctx); err != nil {
+		return model.Link{}, err
+	}
+
+	return link, nil
 }
 
-func (p *Repository) GetShort(ctx context.Context, long string) (string, error) {
+// GetShort find short URL by canonical form of long URL, in user dedup mode only among user URLs
+func (p *Repository) GetShort(ctx context.Context, canonical, id string) (string, error) {
 
-	row := p.pool.QueryRow(ctx, `select short from urls where long = $1`, long)
+	var row pgx.Row
+	if p.dedup == model.DedupUser {
+		row = p.pool.QueryRow(ctx, `select short from urls where canonical = $1 and user_id = $2 and not del_flag`, canonical, id)
+	} else {
+		row = p.pool.QueryRow(ctx, `select short from urls where canonical = $1 and not del_flag limit 1`, canonical)
+	}
 	var out string
 	if err := row.Scan(&out); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", model.ErrNotFound
+		}
 		return "", err
 	}
 
 	return out, nil
 }
 
-func (p *Repository) BatchDelete(batch model.UserRequest) error {
+// BatchDelete mark user URLs as deleted, return IDs that were found
+func (p *Repository) BatchDelete(batch model.UserRequest) ([]string, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), model.TimeOut)
 	defer cancel()
 
-	query := `update urls set del_flag='true' where `
-	args := make([]interface{}, 0)
-	i := 1
-	for _, val := range batch.UserUrls {
-		query += `((user_id=$` + strconv.Itoa(i) + `) and (short=$` + strconv.Itoa(i+1) + `)) OR`
-		i += 2
-		args = append(args, batch.UserID, val)
+	rows, err := p.pool.Query(ctx, `update urls set del_flag = true, deleted_at = coalesce(deleted_at, now())
+		where user_id = $1 and short = any($2) returning short`, batch.UserID, batch.UserUrls)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]string, 0)
+	for rows.Next() {
+		var short string
+		if err := rows.Scan(&short); err != nil {
+			return nil, err
+		}
+		out = append(out, short)
+	}
+
+	return out, rows.Err()
+}
+
+// Restore clear deleted flag of user URLs deleted less than retention ago, return restored IDs,
+// links whose long URL was shortened again after deletion are not restored
+func (p *Repository) Restore(ctx context.Context, batch model.UserRequest, retention time.Duration) ([]string, error) {
+
+	live := `false`
+	switch p.dedup {
+	case model.DedupGlobal:
+		live = `exists (select 1 from urls l where l.canonical = urls.canonical and not l.del_flag)`
+	case model.DedupUser:
+		live = `exists (select 1 from urls l where l.canonical = urls.canonical and l.user_id = urls.user_id and not l.del_flag)`
+	}
+
+	rows, err := p.pool.Query(ctx, `update urls set del_flag = false, deleted_at = null, updated_at = now()
+		where user_id = $1 and short = any($2) and del_flag and deleted_at > $3 and not `+live+` returning short`,
+		batch.UserID, batch.UserUrls, time.Now().Add(-retention))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]string, 0)
+	for rows.Next() {
+		var short string
+		if err := rows.Scan(&short); err != nil {
+			return nil, err
+		}
+		out = append(out, short)
+	}
+	if err := rows.Err(); err != nil {
+		pgerr, ok := err.(*pgconn.PgError)
+		if ok && pgerr.Code == "23505" {
+			return nil, model.ErrConflict
+		}
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Purge remove URLs deleted more than retention ago with their revisions, return count of removed URLs
+func (p *Repository) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+
+	row := p.pool.QueryRow(ctx, `
+	with d as (
+	    delete from urls where del_flag and deleted_at < $1 returning short
+	), r as (
+	    delete from url_revisions where short in (select short from d)
+	), t as (
+	    delete from link_tags where short in (select short from d)
+	)
+	select count(*) from d`, time.Now().Add(-retention))
+	var out int64
+	if err := row.Scan(&out); err != nil {
+		return 0, err
 	}
-	query = query[:len(query)-2]
-	_, err := p.pool.Exec(ctx, query, args...)
+
+	return out, nil
+}
+
+// Click increase clicks counter of short URL and decrease counter of link with click limit,
+// model.ErrExhausted is returned if no clicks are left
+func (p *Repository) Click(ctx context.Context, short string) error {
+
+	tag, err := p.pool.Exec(ctx, `update urls set clicks = clicks + 1, clicks_left = clicks_left - 1
+		where short = $1 and (clicks_left is null or clicks_left > 0)`, short)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrExhausted
+	}
+
+	return nil
+}
+
+// Export pass all user links with metadata to fn ordered by creation time
+func (p *Repository) Export(ctx context.Context, id string, fn func(link model.Link) error) error {
+
+	rows, err := p.pool.Query(ctx, `select `+metaColumns+` from urls
+		where user_id = $1 order by created_at, short`, id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		link := model.Link{}
+		if err := rows.Scan(metaDest(&link)...); err != nil {
+			return err
+		}
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// metaColumns of links returned with metadata by Export and GetPage
+const metaColumns = `short, long, user_id, del_flag, expires_at, created_at, clicks, updated_at, deleted_at, disabled_at, disabled_reason,
+	clicks_left, title, description, array(select tag from link_tags t where t.short = urls.short order by tag)`
+
+// metaDest return scan destinations in order of metaColumns
+func metaDest(link *model.Link) []interface{} {
+	return []interface{}{&link.Short, &link.Long, &link.UserID, &link.Deleted, &link.ExpiresAt, &link.CreatedAt, &link.Clicks, &link.UpdatedAt, &link.DeletedAt,
+		&link.DisabledAt, &link.DisabledReason, &link.ClicksLeft, &link.Title, &link.Description, &link.Tags}
+}
+
+// ShortsByTag return not deleted user short URLs that carry tag
+func (p *Repository) ShortsByTag(ctx context.Context, id, tag string) ([]string, error) {
+
+	rows, err := p.pool.Query(ctx, `select u.short from urls u join link_tags t on t.short = u.short
+		where u.user_id = $1 and t.tag = $2 and not u.del_flag order by u.short`, id, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]string, 0)
+	for rows.Next() {
+		var short string
+		if err := rows.Scan(&short); err != nil {
+			return nil, err
+		}
+		out = append(out, short)
+	}
+
+	return out, rows.Err()
+}
+
+// Stats count all, deleted and created during last 24 hours URLs
+func (p *Repository) Stats(ctx context.Context) (model.Stats, error) {
+
+	var st model.Stats
+	row := p.pool.QueryRow(ctx, `select count(*), count(*) filter (where del_flag),
+		count(*) filter (where created_at > now() - interval '24 hours') from urls`)
+	if err := row.Scan(&st.URLs, &st.Deleted, &st.CreatedDay); err != nil {
+		return model.Stats{}, err
+	}
+
+	return st, nil
+}
+
+// SetDisabled disable link of any user with reason or enable it again
+func (p *Repository) SetDisabled(ctx context.Context, short string, disabled bool, reason string) error {
+
+	tag, err := p.pool.Exec(ctx, `update urls set disabled_at = case when $2 then coalesce(disabled_at, now()) end,
+		disabled_reason = $3 where short = $1`, short, disabled, reason)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrNotFound
+	}
 
 	return nil
 }
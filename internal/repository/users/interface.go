@@ -3,4 +3,7 @@ package users
 type Users interface {
 	AddUserID(user string) error
 	CheckUserID(user string) (bool, error)
+	Count() (int64, error)
+	SetBanned(user string, banned bool) error
+	Banned(user string) (bool, error)
 }
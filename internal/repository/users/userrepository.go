@@ -23,7 +23,8 @@ func NewRepository(cfg config.Config) (*Repository, error) {
 	if _, err := pool.Exec(ctx, `
 	create table if not exists users (	    
 	    user_id varchar(16) unique
-	)
+	);
+	alter table users add column if not exists banned_at timestamptz
 
 `); err != nil {
 		return nil, err
@@ -61,3 +62,46 @@ func (p *Repository) CheckUserID(user string) (bool, error) {
 	}
 	return false, nil
 }
+
+func (p *Repository) Count() (int64, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), model.TimeOut)
+	defer cancel()
+
+	var n int64
+	if err := p.pool.QueryRow(ctx, `select count(*) from users`).Scan(&n); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// SetBanned ban or unban existing user, model.ErrNotFound is returned for unknown user
+func (p *Repository) SetBanned(user string, banned bool) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), model.TimeOut)
+	defer cancel()
+
+	tag, err := p.pool.Exec(ctx, `update users set banned_at = case when $2 then coalesce(banned_at, now()) end where user_id = $1`, user, banned)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrNotFound
+	}
+
+	return nil
+}
+
+func (p *Repository) Banned(user string) (bool, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), model.TimeOut)
+	defer cancel()
+
+	var banned bool
+	if err := p.pool.QueryRow(ctx, `select exists (select 1 from users where user_id = $1 and banned_at is not null)`, user).Scan(&banned); err != nil {
+		return false, err
+	}
+
+	return banned, nil
+}
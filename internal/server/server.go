@@ -6,27 +6,48 @@ import (
 	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
 	"github.com/RomanIkonnikov93/URLshortner/internal/handlers"
 	"github.com/RomanIkonnikov93/URLshortner/internal/repository"
+	"github.com/RomanIkonnikov93/URLshortner/internal/service"
 	"github.com/RomanIkonnikov93/URLshortner/logging"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 )
 
-func StartServer(rep repository.Pool, cfg config.Config, logger logging.Logger) error {
+func StartServer(s *service.Shortener, rep repository.Pool, cfg config.Config, logger logging.Logger) error {
 
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	r.Use(handlers.ClientIP(cfg, logger))
 	r.Use(handlers.GzipRequest)
 	r.Use(handlers.GzipResponse)
 	r.Use(handlers.UserValidation(rep, logger))
 
-	r.Post("/", handlers.PostHandler(rep, logger))
-	r.Post("/api/shorten", handlers.PostJSONHandler(rep, logger))
-	r.Post("/api/shorten/batch", handlers.PostBatchHandler(rep, logger))
-	r.Get("/{id}", handlers.GetHandler(rep, logger))
-	r.Get("/api/user/urls", handlers.GetAllUserURLs(rep, logger))
-	r.Delete("/api/user/urls", handlers.DeleteUserURLs(rep, logger))
+	r.Post("/", handlers.PostHandler(s, logger))
+	r.Post("/api/shorten", handlers.PostJSONHandler(s, logger))
+	r.Post("/api/shorten/batch", handlers.PostBatchHandler(s, logger))
+	r.Get("/{id}", handlers.GetHandler(s, cfg, logger))
+	r.Post("/{id}", handlers.GetHandler(s, cfg, logger))
+	r.Get("/{id}/qr", handlers.GetQR(s, logger))
+	r.Get("/api/user/urls", handlers.GetAllUserURLs(s, logger))
+	r.Get("/api/user/urls/export", handlers.ExportUserURLs(s, logger))
+	r.Patch("/api/user/urls/{id}", handlers.UpdateUserURL(s, logger))
+	r.Delete("/api/user/urls", handlers.DeleteUserURLs(s, logger))
+	r.Post("/api/user/urls/restore", handlers.RestoreUserURLs(s, logger))
+	r.Post("/api/user/urls/import", handlers.ImportUserURLs(s, cfg, logger))
+	r.Get("/api/user/deletions/{job}", handlers.GetDeleteJob(s, logger))
+	r.Delete("/api/user/tags/{tag}/urls", handlers.DeleteTaggedURLs(s, logger))
 	r.Get("/ping", handlers.PingDataBase(rep, logger))
+	r.With(handlers.TrustedSubnet(cfg, logger)).Get("/api/internal/stats", handlers.GetStats(s, logger))
+
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(handlers.AdminAuth(cfg, logger))
+		r.Get("/urls", handlers.AdminSearchURLs(s, logger))
+		r.Post("/urls/{id}/disable", handlers.DisableURL(s, logger))
+		r.Delete("/urls/{id}/disable", handlers.EnableURL(s, logger))
+		r.Post("/users/{user}/ban", handlers.BanUser(s, logger))
+		r.Delete("/users/{user}/ban", handlers.UnbanUser(s, logger))
+		r.Delete("/cache", handlers.ClearCache(s, logger))
+	})
 
 	logger.Info("server running")
 	err := http.ListenAndServe(cfg.ServerAddress, r)
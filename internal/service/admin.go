@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+// DisableLink disable link of any user, it is answered with 451 until enabled
+func (s *Shortener) DisableLink(ctx context.Context, actor, id, reason string) error {
+
+	if err := s.rep.Storage.SetDisabled(ctx, id, true, reason); err != nil {
+		return err
+	}
+
+	return s.audit(ctx, model.AuditEntry{Actor: actor, Action: model.AuditDisableLink, Target: id, Detail: reason})
+}
+
+// ClearCache drop rendered QR codes kept in memory, they are rendered again on next request,
+// return count of dropped images
+func (s *Shortener) ClearCache(ctx context.Context, actor string) (int, error) {
+
+	n := s.qr.Reset()
+
+	return n, s.audit(ctx, model.AuditEntry{Actor: actor, Action: model.AuditClearCache, Detail: strconv.Itoa(n) + " qr codes"})
+}
+
+// EnableLink enable link disabled by DisableLink
+func (s *Shortener) EnableLink(ctx context.Context, actor, id string) error {
+
+	if err := s.rep.Storage.SetDisabled(ctx, id, false, ""); err != nil {
+		return err
+	}
+
+	return s.audit(ctx, model.AuditEntry{Actor: actor, Action: model.AuditEnableLink, Target: id})
+}
+
+// SetBanned ban or unban user, requests with token of banned user are rejected
+func (s *Shortener) SetBanned(ctx context.Context, actor, userID string, banned bool) error {
+
+	if err := s.rep.Users.SetBanned(userID, banned); err != nil {
+		return err
+	}
+
+	action := model.AuditUnbanUser
+	if banned {
+		action = model.AuditBanUser
+	}
+
+	return s.audit(ctx, model.AuditEntry{Actor: actor, Action: action, Target: userID})
+}
+
+// SearchLinks return page of links of all users filtered by query, search is written to audit log
+func (s *Shortener) SearchLinks(ctx context.Context, actor string, q model.ListQuery, raw url.Values) ([]model.Link, string, error) {
+
+	if err := CheckListQuery(&q); err != nil {
+		return nil, "", err
+	}
+
+	links, next, err := s.rep.Storage.GetPage(ctx, q)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = s.audit(ctx, model.AuditEntry{Actor: actor, Action: model.AuditSearchLinks, Target: strings.Trim(q.Domain, "."), Detail: raw.Encode()})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return links, next, nil
+}
+
+// audit write admin action to audit log
+func (s *Shortener) audit(ctx context.Context, entry model.AuditEntry) error {
+
+	if err := s.rep.Audit.Add(ctx, entry); err != nil {
+		s.logger.Errorf("audit %s %s by %s: %s", entry.Action, entry.Target, entry.Actor, err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/random"
+)
+
+// GenerateJobID generate ID of delete job
+func GenerateJobID() string {
+	return random.String(16)
+}
+
+// Delete save pending job and mark user links as deleted in background, return job ID
+func (s *Shortener) Delete(ctx context.Context, userID string, ids []string) (string, error) {
+
+	data := model.UserRequest{UserID: userID, UserUrls: ids}
+	job := model.DeleteJob{
+		ID:     GenerateJobID(),
+		UserID: userID,
+		Status: model.JobPending,
+	}
+	if err := s.rep.Jobs.Create(ctx, job); err != nil {
+		return "", err
+	}
+
+	go func() {
+		deleted, err := s.rep.Storage.BatchDelete(data)
+		if err != nil {
+			s.logger.Error(err)
+			job.Status = model.JobFailed
+		} else {
+			job.Status = model.JobDone
+			job.Results = DeleteResults(data.UserUrls, deleted)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), model.TimeOut)
+		defer cancel()
+		if err := s.rep.Jobs.Finish(ctx, job); err != nil {
+			s.logger.Error(err)
+		}
+	}()
+
+	return job.ID, nil
+}
+
+// DeleteTag start delete job for all user links that carry tag
+func (s *Shortener) DeleteTag(ctx context.Context, userID, tag string) (string, error) {
+
+	shorts, err := s.rep.Storage.ShortsByTag(ctx, userID, strings.ToLower(strings.TrimSpace(tag)))
+	if err != nil {
+		return "", err
+	}
+
+	return s.Delete(ctx, userID, shorts)
+}
+
+// Job return status of user delete job
+func (s *Shortener) Job(ctx context.Context, userID, id string) (model.DeleteJob, error) {
+	return s.rep.Jobs.Get(ctx, id, userID)
+}
+
+// DeleteResults make status for every requested ID
+func DeleteResults(requested, deleted []string) []model.DeleteResult {
+
+	found := make(map[string]bool, len(deleted))
+	for _, val := range deleted {
+		found[val] = true
+	}
+
+	out := make([]model.DeleteResult, 0, len(requested))
+	for _, val := range requested {
+		status := model.DeleteNotFound
+		if found[val] {
+			status = model.DeleteDeleted
+		}
+		out = append(out, model.DeleteResult{ID: val, Status: status})
+	}
+
+	return out
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/importer"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/urlnorm"
+	"github.com/RomanIkonnikov93/URLshortner/internal/validation"
+)
+
+// ImportContext return context for all Import calls of one import, destination hosts are resolved once per import
+func ImportContext(ctx context.Context) context.Context {
+	return validation.WithResolveCache(ctx)
+}
+
+// Import validate parsed import lines, add them to repository and return result for each line,
+// ctx of all chunks of one import should come from ImportContext
+func (s *Shortener) Import(ctx context.Context, userID string, lines []importer.Line) ([]model.ImportResult, error) {
+
+	out := make([]model.ImportResult, len(lines))
+	links := make([]model.Link, 0, len(lines))
+	generated := make([]bool, 0, len(lines))
+	idx := make([]int, 0, len(lines))
+
+	for i, line := range lines {
+		out[i] = model.ImportResult{Line: line.N, Status: model.StatusError}
+
+		link, err := s.importLink(ctx, userID, line)
+		if err != nil {
+			out[i].Err = err
+			continue
+		}
+		links = append(links, link)
+		generated = append(generated, line.Record.Alias == "")
+		idx = append(idx, i)
+	}
+
+	if len(links) == 0 {
+		return out, nil
+	}
+
+	res, err := s.addBatch(ctx, links, generated)
+	if err != nil {
+		return nil, err
+	}
+	for j, val := range res {
+		i := idx[j]
+		out[i].Status = val.Status
+		if val.Err != nil {
+			out[i].Err = val.Err
+			continue
+		}
+		out[i].ShortURL = s.ShortURL(val.Short)
+	}
+
+	return out, nil
+}
+
+func (s *Shortener) importLink(ctx context.Context, userID string, line importer.Line) (model.Link, error) {
+
+	if line.Err != nil {
+		return model.Link{}, fmt.Errorf("%w: %s", model.ErrImportLine, line.Err)
+	}
+	rec := line.Record
+
+	_, err := s.v.Check(ctx, rec.URL)
+	if err != nil {
+		return model.Link{}, err
+	}
+
+	canonical, err := urlnorm.Canonical(rec.URL, s.cfg.StripTrackingParams)
+	if err != nil {
+		return model.Link{}, fmt.Errorf("%w: %s", model.ErrInvalidURL, err)
+	}
+
+	short := rec.Alias
+	if short == "" {
+		short = Short()
+	} else if !ValidAlias(short) {
+		return model.Link{}, model.ErrAlias
+	}
+
+	if rec.ExpiresAt != nil && !rec.ExpiresAt.After(time.Now()) {
+		return model.Link{}, model.ErrExpiresAt
+	}
+
+	return model.Link{
+		Short:     short,
+		Long:      rec.URL,
+		Canonical: canonical,
+		UserID:    userID,
+		LinkOptions: model.LinkOptions{
+			RedirectCode: model.DefaultRedirectCode,
+			ExpiresAt:    rec.ExpiresAt,
+		},
+	}, nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+// CheckListQuery validate limit and sort of links list, set defaults and normalize domain and tag
+func CheckListQuery(q *model.ListQuery) error {
+
+	switch {
+	case q.Limit == 0:
+		q.Limit = model.DefaultPageSize
+	case q.Limit < 0 || q.Limit > model.MaxPageSize:
+		return fmt.Errorf("%w: limit must be from 1 to %d", model.ErrListQuery, model.MaxPageSize)
+	}
+
+	switch q.Sort {
+	case "":
+		q.Sort = model.SortCreatedAt
+	case model.SortCreatedAt, model.SortClicks:
+	default:
+		return fmt.Errorf("%w: sort must be created_at or clicks", model.ErrListQuery)
+	}
+
+	q.Domain = strings.Trim(strings.ToLower(q.Domain), ".")
+	q.Tag = strings.ToLower(strings.TrimSpace(q.Tag))
+
+	return nil
+}
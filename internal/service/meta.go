@@ -0,0 +1,59 @@
+package service
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+// Limits of link metadata
+const (
+	maxTitle       = 200
+	maxDescription = 2000
+	maxTags        = 20
+	maxTag         = 64
+)
+
+// CheckLinkMeta validate title, description and tags, tags are trimmed, lower cased and deduplicated
+func CheckLinkMeta(meta *model.LinkMeta) error {
+
+	if utf8.RuneCountInString(meta.Title) > maxTitle {
+		return model.ErrTitle
+	}
+	if utf8.RuneCountInString(meta.Description) > maxDescription {
+		return model.ErrDescription
+	}
+
+	tags, err := NormalizeTags(meta.Tags)
+	if err != nil {
+		return err
+	}
+	meta.Tags = tags
+
+	return nil
+}
+
+// NormalizeTags trim, lower case and deduplicate tags
+func NormalizeTags(tags []string) ([]string, error) {
+
+	if len(tags) > maxTags {
+		return nil, model.ErrTags
+	}
+
+	seen := make(map[string]bool, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, val := range tags {
+		tag := strings.ToLower(strings.TrimSpace(val))
+		if tag == "" || utf8.RuneCountInString(tag) > maxTag {
+			return nil, model.ErrTags
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxPassword bcrypt ignores bytes after 72
+const maxPassword = 72
+
+// HashPassword return bcrypt hash of link password, empty password means link is not protected
+func HashPassword(password string) (string, error) {
+
+	if password == "" {
+		return "", nil
+	}
+	if len(password) > maxPassword {
+		return "", model.ErrPassword
+	}
+
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(h), nil
+}
+
+// checkPassword verify password of visit to protected link, wrong passwords are limited per IP and link
+func (s *Shortener) checkPassword(link model.Link, visit model.Visit) error {
+
+	if link.PasswordHash == "" {
+		return nil
+	}
+	if visit.Token != "" && validPasswordToken(link, visit.Token, time.Now()) {
+		return nil
+	}
+	if visit.Password == nil {
+		return model.ErrPasswordRequired
+	}
+
+	// attempt is counted before bcrypt, so parallel guesses can't pass the limit
+	key := visit.IP + "|" + link.Short
+	if !s.attempts.reserve(key) {
+		return model.ErrTooManyAttempts
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(*visit.Password)); err != nil {
+		return model.ErrPasswordWrong
+	}
+	s.attempts.reset(key)
+
+	return nil
+}
+
+// passwordTokenTTL lifetime of token given on preview page of protected link
+const passwordTokenTTL = 10 * time.Minute
+
+// passwordToken sign link short URL and password hash with expiry, changed password invalidates token
+func passwordToken(link model.Link, exp time.Time) string {
+	e := strconv.FormatInt(exp.Unix(), 10)
+	return e + "." + passwordTokenSig(link, e)
+}
+
+func passwordTokenSig(link model.Link, exp string) string {
+	mac := hmac.New(sha256.New, model.Key)
+	mac.Write([]byte(link.Short + "|" + link.PasswordHash + "|" + exp))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validPasswordToken check signature and expiry of token
+func validPasswordToken(link model.Link, token string, now time.Time) bool {
+
+	e, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	exp, err := strconv.ParseInt(e, 10, 64)
+	if err != nil || now.Unix() > exp {
+		return false
+	}
+
+	return hmac.Equal([]byte(sig), []byte(passwordTokenSig(link, e)))
+}
+
+// attemptLimiter count attempts by key during window, successful attempt resets the count
+type attemptLimiter struct {
+	mu     sync.Mutex
+	max    int
+	window time.Duration
+	swept  time.Time
+	keys   map[string]*attempts
+}
+
+type attempts struct {
+	count int
+	until time.Time
+}
+
+func newAttemptLimiter(max int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{max: max, window: window, keys: make(map[string]*attempts)}
+}
+
+// reserve count attempt of key if it has attempts left, limit is disabled if max <= 0,
+// expired keys are removed once per window
+func (l *attemptLimiter) reserve(key string) bool {
+
+	if l.max <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.swept) > l.window {
+		for k, a := range l.keys {
+			if now.After(a.until) {
+				delete(l.keys, k)
+			}
+		}
+		l.swept = now
+	}
+
+	a, ok := l.keys[key]
+	if !ok || now.After(a.until) {
+		a = &attempts{until: now.Add(l.window)}
+		l.keys[key] = a
+	}
+	if a.count >= l.max {
+		return false
+	}
+	a.count++
+
+	return true
+}
+
+// reset forget attempts after successful one
+func (l *attemptLimiter) reset(key string) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.keys, key)
+}
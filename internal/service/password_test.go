@@ -0,0 +1,118 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+
+	l := newAttemptLimiter(3, 50*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if !l.reserve("ip|a") {
+			t.Fatalf("attempt %d rejected", i+1)
+		}
+	}
+	if l.reserve("ip|a") {
+		t.Fatal("attempt over limit allowed")
+	}
+	if !l.reserve("ip|b") {
+		t.Fatal("attempt of other key rejected")
+	}
+
+	time.Sleep(70 * time.Millisecond)
+	if !l.reserve("ip|a") {
+		t.Fatal("attempt after window rejected")
+	}
+
+	l.reset("ip|a")
+	for i := 0; i < 3; i++ {
+		if !l.reserve("ip|a") {
+			t.Fatalf("attempt %d after reset rejected", i+1)
+		}
+	}
+}
+
+func TestAttemptLimiterDisabled(t *testing.T) {
+
+	l := newAttemptLimiter(0, time.Minute)
+	for i := 0; i < 100; i++ {
+		if !l.reserve("ip|a") {
+			t.Fatal("attempt rejected by disabled limiter")
+		}
+	}
+}
+
+func TestAttemptLimiterConcurrent(t *testing.T) {
+
+	const max, workers = 5, 50
+	l := newAttemptLimiter(max, time.Minute)
+
+	var allowed int64
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if l.reserve("ip|a") {
+				atomic.AddInt64(&allowed, 1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if allowed != max {
+		t.Fatalf("%d parallel attempts allowed, want %d", allowed, max)
+	}
+}
+
+func TestPasswordToken(t *testing.T) {
+
+	link := model.Link{Short: "abc", PasswordHash: "hash"}
+	now := time.Now()
+	token := passwordToken(link, now.Add(passwordTokenTTL))
+
+	exp, sig, _ := strings.Cut(token, ".")
+	tampered := []byte(sig)
+	if tampered[0] == 'A' {
+		tampered[0] = 'B'
+	} else {
+		tampered[0] = 'A'
+	}
+
+	tests := []struct {
+		name  string
+		link  model.Link
+		token string
+		now   time.Time
+		want  bool
+	}{
+		{"valid", link, token, now, true},
+		{"valid until expiry", link, token, now.Add(passwordTokenTTL), true},
+		{"expired", link, token, now.Add(passwordTokenTTL + time.Second), false},
+		{"expiry moved", link, "9999999999." + sig, now, false},
+		{"signature changed", link, exp + "." + string(tampered), now, false},
+		{"other link", model.Link{Short: "abd", PasswordHash: "hash"}, token, now, false},
+		{"password changed", model.Link{Short: "abc", PasswordHash: "other"}, token, now, false},
+		{"no signature", link, exp, now, false},
+		{"garbage", link, "x.y", now, false},
+		{"empty", link, "", now, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validPasswordToken(tt.link, tt.token, tt.now); got != tt.want {
+				t.Fatalf("validPasswordToken(%q) = %v, want %v", tt.token, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+// Safety warnings shown on preview page
+const (
+	WarnNoHTTPS         = "The destination does not use HTTPS, data sent to it is not encrypted."
+	WarnIPHost          = "The destination is an IP address instead of a domain name."
+	WarnPunycode        = "The destination domain contains international characters that can imitate a known domain."
+	WarnCredentials     = "The destination URL contains a user name or password."
+	WarnNonStandardPort = "The destination uses a non-standard port."
+)
+
+// Preview return destination chosen by link rules and metadata of link for preview page, click is not counted,
+// destination of protected link is shown only with its password and token for continue link is returned
+func (s *Shortener) Preview(ctx context.Context, id string, visit model.Visit) (model.Preview, error) {
+
+	link, err := s.rep.Storage.Get(ctx, id)
+	if err != nil {
+		return model.Preview{}, err
+	}
+	if err := s.checkPassword(link, visit); err != nil {
+		return model.Preview{}, err
+	}
+	token := ""
+	if link.PasswordHash != "" {
+		token = passwordToken(link, time.Now().Add(passwordTokenTTL))
+	}
+	link.Long = s.destination(link, visit)
+
+	p := model.Preview{
+		Short:        id,
+		Long:         link.Long,
+		CreatedAt:    link.CreatedAt,
+		ExpiresAt:    link.ExpiresAt,
+		Title:        link.Title,
+		Description:  link.Description,
+		Interstitial: link.Interstitial,
+		Token:        token,
+	}
+	if u, err := url.Parse(link.Long); err == nil {
+		p.Host = u.Hostname()
+		p.Warnings = Warnings(u)
+	}
+
+	return p, nil
+}
+
+// Warnings return safety warnings about destination URL
+func Warnings(u *url.URL) []string {
+
+	out := make([]string, 0)
+	if !strings.EqualFold(u.Scheme, "https") {
+		out = append(out, WarnNoHTTPS)
+	}
+
+	host := u.Hostname()
+	if net.ParseIP(host) != nil {
+		out = append(out, WarnIPHost)
+	}
+	if internationalHost(host) {
+		out = append(out, WarnPunycode)
+	}
+
+	if u.User != nil {
+		out = append(out, WarnCredentials)
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		out = append(out, WarnNonStandardPort)
+	}
+
+	return out
+}
+
+// internationalHost check if host has non-ASCII characters or punycode labels
+func internationalHost(host string) bool {
+	for _, r := range host {
+		if r > unicode.MaxASCII {
+			return true
+		}
+	}
+	for _, label := range strings.Split(strings.ToLower(host), ".") {
+		if strings.HasPrefix(label, "xn--") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/qr"
+)
+
+// QRURL make URL of QR code image of short URL
+func QRURL(short string) string {
+	return short + "/qr"
+}
+
+// QR return QR code image of short URL, link must be available for redirect
+func (s *Shortener) QR(ctx context.Context, id string, o qr.Options) ([]byte, error) {
+
+	if _, err := s.rep.Storage.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.qr.Get(s.ShortURL(id), o)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+// CheckLinkOptions validate redirect options and set default redirect code
+func CheckLinkOptions(opts *model.LinkOptions) error {
+	if opts.RedirectCode == 0 {
+		opts.RedirectCode = model.DefaultRedirectCode
+	}
+	if !ValidRedirectCode(opts.RedirectCode) {
+		return model.ErrRedirectCode
+	}
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(time.Now()) {
+		return model.ErrExpiresAt
+	}
+	if opts.MaxClicks < 0 {
+		return model.ErrMaxClicks
+	}
+	return nil
+}
+
+// ValidRedirectCode check that code is one of supported redirect statuses
+func ValidRedirectCode(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// RedirectTarget make redirect URL from link options and incoming query,
+// query of long URL is kept as it is, UTM parameters it lacks and passed through query are appended,
+// passed through parameters replace parameters of long URL with the same name
+func RedirectTarget(link model.Link, query url.Values) (string, error) {
+
+	if link.UTM == nil && (!link.QueryPassthrough || len(query) == 0) {
+		return link.Long, nil
+	}
+
+	u, err := url.Parse(link.Long)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	extra := make(url.Values)
+	if link.UTM != nil {
+		for key, val := range link.UTM.Values() {
+			if !q.Has(key) {
+				extra[key] = val
+			}
+		}
+	}
+	raw := u.RawQuery
+	if link.QueryPassthrough && len(query) > 0 {
+		for key, val := range query {
+			extra[key] = val
+		}
+		raw = dropParams(raw, query)
+	}
+	if len(extra) == 0 {
+		return link.Long, nil
+	}
+
+	if raw != "" {
+		raw += "&"
+	}
+	u.RawQuery = raw + extra.Encode()
+
+	return u.String(), nil
+}
+
+// dropParams remove parameters named in drop from raw query, other parameters are kept byte for byte
+func dropParams(raw string, drop url.Values) string {
+
+	parts := strings.Split(raw, "&")
+	out := parts[:0]
+	for _, part := range parts {
+		key := part
+		if i := strings.Index(key, "="); i >= 0 {
+			key = key[:i]
+		}
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if _, ok := drop[key]; ok {
+			continue
+		}
+		out = append(out, part)
+	}
+	return strings.Join(out, "&")
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+func TestRedirectTarget(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		long        string
+		passthrough bool
+		utm         *model.UTM
+		query       string
+		want        string
+	}{
+		{"no options", "https://x.example/?z=1&y", false, nil, "a=1", "https://x.example/?z=1&y"},
+		{"passthrough without query", "https://x.example/?z=1&y", true, nil, "", "https://x.example/?z=1&y"},
+		{"order and bare flag kept", "https://x.example/p?z=1&y&sig=AbC%2F", true, nil, "a=1", "https://x.example/p?z=1&y&sig=AbC%2F&a=1"},
+		{"passed parameter replaces own", "https://x.example/?z=1&a=old&y", true, nil, "a=new", "https://x.example/?z=1&y&a=new"},
+		{"escaped name replaced", "https://x.example/?a%5B%5D=1&b=2", true, nil, "a[]=3", "https://x.example/?b=2&a%5B%5D=3"},
+		{"no own query", "https://x.example/p", true, nil, "b=2&a=1", "https://x.example/p?a=1&b=2"},
+		{"fragment kept", "https://x.example/p?z=1#top", true, nil, "a=1", "https://x.example/p?z=1&a=1#top"},
+		{"utm appended", "https://x.example/?z=1&y", false, &model.UTM{Source: "news", Medium: "email"}, "", "https://x.example/?z=1&y&utm_medium=email&utm_source=news"},
+		{"own utm wins", "https://x.example/?utm_source=own", false, &model.UTM{Source: "news"}, "", "https://x.example/?utm_source=own"},
+		{"passed utm wins", "https://x.example/", true, &model.UTM{Source: "news"}, "utm_source=ad", "https://x.example/?utm_source=ad"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			link := model.Link{Long: tt.long, LinkOptions: model.LinkOptions{QueryPassthrough: tt.passthrough, UTM: tt.utm}}
+
+			got, err := RedirectTarget(link, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("RedirectTarget = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"golang.org/x/text/language"
+)
+
+// Limits of link rules
+const (
+	maxRules    = 20
+	maxLanguage = 35
+)
+
+// CheckRules validate conditions and destinations of link rules and normalize their case
+func (s *Shortener) CheckRules(ctx context.Context, rules []model.Rule) ([]model.Rule, error) {
+
+	if len(rules) > maxRules {
+		return nil, model.ErrRules
+	}
+
+	out := make([]model.Rule, 0, len(rules))
+	for _, val := range rules {
+		r := model.Rule{
+			Platform: strings.ToLower(strings.TrimSpace(val.Platform)),
+			Language: strings.ToLower(strings.TrimSpace(val.Language)),
+			Country:  strings.ToUpper(strings.TrimSpace(val.Country)),
+			URL:      val.URL,
+		}
+		switch r.Platform {
+		case "", model.PlatformIOS, model.PlatformAndroid, model.PlatformDesktop:
+		default:
+			return nil, model.ErrRules
+		}
+		if r.Platform == "" && r.Language == "" && r.Country == "" {
+			return nil, model.ErrRules
+		}
+		if len(r.Language) > maxLanguage || (r.Country != "" && len(r.Country) != 2) {
+			return nil, model.ErrRules
+		}
+		if r.Country != "" && s.geo == nil {
+			return nil, fmt.Errorf("%w: country rules need GeoIP database", model.ErrRules)
+		}
+		if _, err := s.v.Check(ctx, r.URL); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+
+	return out, nil
+}
+
+// destination choose long URL of visit by link rules, long URL of link is returned if no rule matches,
+// of matching rules the one for the most preferred language of client wins, rule without language
+// counts as rule for the most preferred one, first rule wins among equal
+func (s *Shortener) destination(link model.Link, visit model.Visit) string {
+
+	if len(link.Rules) == 0 {
+		return link.Long
+	}
+
+	platform := Platform(visit.UserAgent)
+	languages := Languages(visit.AcceptLanguage)
+	country := ""
+	for _, r := range link.Rules {
+		if r.Country != "" {
+			country = s.geo.Country(visit.IP)
+			break
+		}
+	}
+
+	best, rank := link.Long, len(languages)+1
+	for _, r := range link.Rules {
+		if r.Platform != "" && r.Platform != platform {
+			continue
+		}
+		if r.Country != "" && r.Country != country {
+			continue
+		}
+		i := 0
+		if r.Language != "" {
+			if i = languageRank(r.Language, languages); i < 0 {
+				continue
+			}
+		}
+		if i < rank {
+			best, rank = r.URL, i
+		}
+		if rank == 0 {
+			break
+		}
+	}
+
+	return best
+}
+
+// Platform detect ios, android or desktop from User-Agent
+func Platform(ua string) string {
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"), strings.Contains(ua, "iPod"):
+		return model.PlatformIOS
+	case strings.Contains(ua, "Android"):
+		return model.PlatformAndroid
+	}
+	return model.PlatformDesktop
+}
+
+// Languages return lowercase language tags of Accept-Language from the most preferred one,
+// tags with zero weight, wildcard and invalid tags are skipped
+func Languages(header string) []string {
+
+	tags, q, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return nil
+	}
+
+	out := make([]string, 0, len(tags))
+	for i, tag := range tags {
+		// wildcard is parsed as "mul"
+		name := strings.ToLower(tag.String())
+		if q[i] <= 0 || tag == language.Und || name == "mul" {
+			continue
+		}
+		out = append(out, name)
+	}
+	return out
+}
+
+// languageRank return index of the first client tag matched by rule language, -1 if none matches,
+// rule "en" matches "en-us"
+func languageRank(rule string, tags []string) int {
+	for i, tag := range tags {
+		if tag == rule || strings.HasPrefix(tag, rule+"-") {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+)
+
+func TestLanguages(t *testing.T) {
+
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"da, en;q=0.8", []string{"da", "en"}},
+		{"en;q=0.5, de, fr;q=0.9", []string{"de", "fr", "en"}},
+		{"en-US,en;q=0.9,*;q=0.1", []string{"en-us", "en"}},
+		{"fr;q=0, de", []string{"de"}},
+		{"en;q=abc", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got := Languages(tt.header)
+			if got == nil {
+				got = []string{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Languages(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDestination(t *testing.T) {
+
+	const (
+		iphone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X)"
+		android = "Mozilla/5.0 (Linux; Android 13)"
+		desktop = "Mozilla/5.0 (X11; Linux x86_64)"
+	)
+	rules := []model.Rule{
+		{Language: "de", URL: "de"},
+		{Platform: model.PlatformIOS, URL: "ios"},
+		{Language: "en", URL: "en"},
+		{Platform: model.PlatformAndroid, Language: "fr", URL: "android-fr"},
+	}
+	s := &Shortener{}
+
+	tests := []struct {
+		name  string
+		ua    string
+		lang  string
+		rules []model.Rule
+		want  string
+	}{
+		{"no rules", desktop, "de", nil, "long"},
+		{"no match", desktop, "es", rules, "long"},
+		{"first language", desktop, "en", rules, "en"},
+		{"less preferred language", desktop, "da, en;q=0.8", rules, "en"},
+		{"more preferred language wins over rule order", desktop, "en, de;q=0.5", rules, "en"},
+		{"rule order among equal", iphone, "de", rules, "de"},
+		{"rule without language for most preferred", iphone, "en", rules, "ios"},
+		{"platform rule over rule of less preferred language", iphone, "es, en;q=0.5", rules, "ios"},
+		{"all conditions", android, "fr-CA, en;q=0.9", rules, "android-fr"},
+		{"not all conditions", desktop, "fr", rules, "long"},
+		{"region of rule language", desktop, "en-GB", rules, "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := model.Link{Long: "long", LinkOptions: model.LinkOptions{Rules: tt.rules}}
+			got := s.destination(link, model.Visit{UserAgent: tt.ua, AcceptLanguage: tt.lang})
+			if got != tt.want {
+				t.Fatalf("destination = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRulesCountryWithoutGeoIP(t *testing.T) {
+
+	s := &Shortener{}
+	_, err := s.CheckRules(context.Background(), []model.Rule{{Country: "de", URL: "https://x.example/"}})
+	if !errors.Is(err, model.ErrRules) {
+		t.Fatalf("CheckRules error = %v, want %v", err, model.ErrRules)
+	}
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"regexp"
+
+	"github.com/RomanIkonnikov93/URLshortner/internal/random"
+)
+
+// aliasRe allowed custom short URLs
+var aliasRe = regexp.MustCompile(`^[A-Za-z0-9_-]{3,32}$`)
+
+// reservedAliases clash with service routes
+var reservedAliases = map[string]bool{
+	"api":  true,
+	"ping": true,
+}
+
+// shortRetries new short URLs tried when generated one is already taken
+const shortRetries = 5
+
+// Short generate short URL
+func Short() string {
+	return random.String(5)
+}
+
+// ValidAlias check custom short URL
+func ValidAlias(alias string) bool {
+	return aliasRe.MatchString(alias) && !reservedAliases[alias]
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+	"github.com/RomanIkonnikov93/URLshortner/internal/geoip"
+	"github.com/RomanIkonnikov93/URLshortner/internal/model"
+	"github.com/RomanIkonnikov93/URLshortner/internal/qr"
+	"github.com/RomanIkonnikov93/URLshortner/internal/repository"
+	"github.com/RomanIkonnikov93/URLshortner/internal/urlnorm"
+	"github.com/RomanIkonnikov93/URLshortner/internal/validation"
+	"github.com/RomanIkonnikov93/URLshortner/logging"
+)
+
+// Shortener business logic of links shared by HTTP and gRPC APIs, methods return domain errors
+type Shortener struct {
+	rep    repository.Pool
+	v      *validation.Validator
+	cfg    config.Config
+	logger logging.Logger
+	qr     *qr.Cache
+	geo    *geoip.Locator
+
+	// wrong passwords of protected links
+	attempts *attemptLimiter
+}
+
+// NewShortener create Shortener over repository, geo may be nil if country rules are not used
+func NewShortener(rep repository.Pool, v *validation.Validator, geo *geoip.Locator, cfg config.Config, logger logging.Logger) *Shortener {
+	return &Shortener{
+		rep:      rep,
+		v:        v,
+		geo:      geo,
+		cfg:      cfg,
+		logger:   logger,
+		qr:       qr.NewCache(cfg.QRCacheSize),
+		attempts: newAttemptLimiter(cfg.PasswordAttempts, cfg.PasswordWindow),
+	}
+}
+
+// ShortURL make short URL of link ID on public base URL
+func (s *Shortener) ShortURL(id string) string {
+	return strings.TrimSuffix(s.cfg.BaseURL, "/") + "/" + id
+}
+
+// Shorten validate long URL and options, save new link and return short URL,
+// for duplicate long URL existing short URL is returned with model.ErrConflict
+func (s *Shortener) Shorten(ctx context.Context, userID string, req model.URLRequest) (string, error) {
+
+	link, err := s.NewLink(ctx, userID, req.URL, req.LinkOptions, req.LinkMeta)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.rep.Storage.Add(ctx, link)
+	for i := 0; i < shortRetries && errors.Is(err, model.ErrAliasTaken); i++ {
+		link.Short = Short()
+		err = s.rep.Storage.Add(ctx, link)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			short, err := s.rep.Storage.GetShort(ctx, link.Canonical, userID)
+			if err != nil {
+				return "", err
+			}
+			return s.ShortURL(short), model.ErrConflict
+		}
+		return "", err
+	}
+
+	return s.ShortURL(link.Short), nil
+}
+
+// ShortenBatch add batch URLs in one transaction, return short URL and status of each,
+// invalid items are reported without stopping the batch
+func (s *Shortener) ShortenBatch(ctx context.Context, userID string, batch model.BatchRequest) ([]*model.BatchResponse, error) {
+
+	if s.cfg.BatchMaxSize > 0 && len(batch) > s.cfg.BatchMaxSize {
+		return nil, fmt.Errorf("%w: at most %d items are allowed", model.ErrBatchTooLarge, s.cfg.BatchMaxSize)
+	}
+
+	arr := make([]*model.BatchResponse, len(batch))
+	links := make([]model.Link, 0, len(batch))
+	idx := make([]int, 0, len(batch))
+	for i, val := range batch {
+		arr[i] = &model.BatchResponse{CorrelationID: val.CorrelationID, Status: model.StatusError}
+
+		link, err := s.NewLink(ctx, userID, val.OriginalURL, val.LinkOptions, val.LinkMeta)
+		if err != nil {
+			s.logger.Warn(err)
+			arr[i].Err = err
+			continue
+		}
+		links = append(links, link)
+		idx = append(idx, i)
+	}
+
+	if len(links) == 0 {
+		return arr, nil
+	}
+
+	generated := make([]bool, len(links))
+	for j := range generated {
+		generated[j] = true
+	}
+	res, err := s.addBatch(ctx, links, generated)
+	if err != nil {
+		return nil, err
+	}
+	for j, val := range res {
+		i := idx[j]
+		arr[i].Status = val.Status
+		if val.Err != nil {
+			arr[i].Err = val.Err
+			continue
+		}
+		arr[i].ShortURL = s.ShortURL(val.Short)
+	}
+
+	return arr, nil
+}
+
+// addBatch add links in batch, links with generated short URL that is already taken get new one and are added again
+func (s *Shortener) addBatch(ctx context.Context, links []model.Link, generated []bool) ([]model.AddResult, error) {
+
+	res, err := s.rep.Storage.AddBatch(ctx, links)
+	if err != nil {
+		return nil, err
+	}
+
+	for try := 0; try < shortRetries; try++ {
+		retry := make([]int, 0)
+		for i, val := range res {
+			if generated[i] && errors.Is(val.Err, model.ErrAliasTaken) {
+				retry = append(retry, i)
+			}
+		}
+		if len(retry) == 0 {
+			break
+		}
+
+		again := make([]model.Link, len(retry))
+		for j, i := range retry {
+			links[i].Short = Short()
+			again[j] = links[i]
+		}
+		r, err := s.rep.Storage.AddBatch(ctx, again)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range retry {
+			res[i] = r[j]
+		}
+	}
+
+	return res, nil
+}
+
+// Resolve get link by short URL ID, check password, count click and return redirect target chosen by link rules and code,
+// model.ErrInterstitial is returned for interstitial link until redirect is confirmed on preview page,
+// model.ErrExhausted if link with click limit has no clicks left
+func (s *Shortener) Resolve(ctx context.Context, id string, visit model.Visit) (string, int, error) {
+
+	link, err := s.rep.Storage.Get(ctx, id)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := s.checkPassword(link, visit); err != nil {
+		return "", 0, err
+	}
+	if link.Interstitial && !visit.Confirmed {
+		return "", 0, model.ErrInterstitial
+	}
+
+	link.Long = s.destination(link, visit)
+	target, err := RedirectTarget(link, visit.Query)
+	if err != nil {
+		return "", 0, err
+	}
+	code := link.RedirectCode
+	if code == 0 {
+		code = model.DefaultRedirectCode
+	}
+
+	// click of limited link is taken before redirect, so concurrent visits can't exceed the limit
+	if err := s.rep.Storage.Click(ctx, link.Short); err != nil {
+		if link.ClicksLeft != nil || errors.Is(err, model.ErrExhausted) {
+			return "", 0, err
+		}
+		s.logger.Error(err)
+	}
+
+	return target, code, nil
+}
+
+// List return page of user links and cursor of the next page
+func (s *Shortener) List(ctx context.Context, q model.ListQuery) ([]model.Link, string, error) {
+	if err := CheckListQuery(&q); err != nil {
+		return nil, "", err
+	}
+	return s.rep.Storage.GetPage(ctx, q)
+}
+
+// Update validate new target, options and tags and change user link
+func (s *Shortener) Update(ctx context.Context, userID, id string, upd model.URLUpdateRequest) (model.Link, error) {
+
+	var err error
+	if upd.URL != nil {
+		_, err = s.v.Check(ctx, *upd.URL)
+		if err != nil {
+			return model.Link{}, err
+		}
+		upd.Canonical, err = urlnorm.Canonical(*upd.URL, s.cfg.StripTrackingParams)
+		if err != nil {
+			return model.Link{}, fmt.Errorf("%w: %s", model.ErrInvalidURL, err)
+		}
+	}
+	if upd.RedirectCode != nil && !ValidRedirectCode(*upd.RedirectCode) {
+		return model.Link{}, model.ErrRedirectCode
+	}
+	if upd.ExpiresAt.Time != nil && !upd.ExpiresAt.Time.After(time.Now()) {
+		return model.Link{}, model.ErrExpiresAt
+	}
+	if upd.MaxClicks != nil && *upd.MaxClicks < 0 {
+		return model.Link{}, model.ErrMaxClicks
+	}
+	if upd.Tags != nil {
+		tags, err := NormalizeTags(*upd.Tags)
+		if err != nil {
+			return model.Link{}, err
+		}
+		upd.Tags = &tags
+	}
+	if upd.Rules != nil {
+		rules, err := s.CheckRules(ctx, *upd.Rules)
+		if err != nil {
+			return model.Link{}, err
+		}
+		upd.Rules = &rules
+	}
+	if upd.Password != nil {
+		upd.PasswordHash, err = HashPassword(*upd.Password)
+		if err != nil {
+			return model.Link{}, err
+		}
+	}
+
+	return s.rep.Storage.Update(ctx, id, userID, upd)
+}
+
+// Restore restore user links deleted within retention window, return restored IDs
+func (s *Shortener) Restore(ctx context.Context, userID string, ids []string) ([]string, error) {
+	return s.rep.Storage.Restore(ctx, model.UserRequest{UserID: userID, UserUrls: ids}, s.cfg.DeleteRetention)
+}
+
+// Export call fn for every user link
+func (s *Shortener) Export(ctx context.Context, userID string, fn func(link model.Link) error) error {
+	return s.rep.Storage.Export(ctx, userID, fn)
+}
+
+// NewLink validate long URL, options and metadata and make link with new short URL for repository
+func (s *Shortener) NewLink(ctx context.Context, userID, long string, opts model.LinkOptions, meta model.LinkMeta) (model.Link, error) {
+
+	_, err := s.v.Check(ctx, long)
+	if err != nil {
+		return model.Link{}, err
+	}
+
+	err = CheckLinkOptions(&opts)
+	if err != nil {
+		return model.Link{}, err
+	}
+
+	err = CheckLinkMeta(&meta)
+	if err != nil {
+		return model.Link{}, err
+	}
+
+	opts.Rules, err = s.CheckRules(ctx, opts.Rules)
+	if err != nil {
+		return model.Link{}, err
+	}
+
+	canonical, err := urlnorm.Canonical(long, s.cfg.StripTrackingParams)
+	if err != nil {
+		return model.Link{}, fmt.Errorf("%w: %s", model.ErrInvalidURL, err)
+	}
+
+	hash, err := HashPassword(opts.Password)
+	if err != nil {
+		return model.Link{}, err
+	}
+	opts.Password = ""
+
+	return model.Link{
+		Short:        Short(),
+		Long:         long,
+		Canonical:    canonical,
+		UserID:       userID,
+		PasswordHash: hash,
+		LinkOptions:  opts,
+		LinkMeta:     meta,
+	}, nil
+}
+
+// Stats return counts of URLs and users
+func (s *Shortener) Stats(ctx context.Context) (model.Stats, error) {
+
+	st, err := s.rep.Storage.Stats(ctx)
+	if err != nil {
+		return model.Stats{}, err
+	}
+
+	st.Users, err = s.rep.Users.Count()
+	if err != nil {
+		return model.Stats{}, err
+	}
+
+	return st, nil
+}
@@ -0,0 +1,85 @@
+package urlnorm
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// trackingParams removed from query when tracking parameters are stripped
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"yclid":   true,
+	"msclkid": true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Canonical return canonical form of URL used to find duplicates:
+// lower case scheme and host, punycode host, no default port, no trailing slash
+// and sorted query without tracking parameters if stripTracking is set
+func Canonical(raw string, stripTracking bool) (string, error) {
+
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if net.ParseIP(host) == nil {
+		host, err = idna.Lookup.ToASCII(host)
+		if err != nil {
+			return "", err
+		}
+	}
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	// trailing slash is trimmed on escaped path, escaped bytes like %2F stay as they are
+	path := u.EscapedPath()
+	if len(path) > 1 {
+		path = strings.TrimRight(path, "/")
+	}
+	if path == "" {
+		path = "/"
+	}
+	u.Path, err = url.PathUnescape(path)
+	if err != nil {
+		return "", err
+	}
+	u.RawPath = path
+
+	q := u.Query()
+	if stripTracking {
+		for key := range q {
+			if strings.HasPrefix(strings.ToLower(key), "utm_") || trackingParams[strings.ToLower(key)] {
+				q.Del(key)
+			}
+		}
+	}
+	u.RawQuery = q.Encode()
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
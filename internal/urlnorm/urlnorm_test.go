@@ -0,0 +1,59 @@
+package urlnorm
+
+import "testing"
+
+func TestCanonical(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		raw   string
+		strip bool
+		want  string
+	}{
+		{"trailing slash", "http://x.com/a/b/", false, "http://x.com/a/b"},
+		{"many trailing slashes", "http://x.com/a//", false, "http://x.com/a"},
+		{"root", "http://x.com", false, "http://x.com/"},
+		{"root slashes", "http://x.com//", false, "http://x.com/"},
+		{"escaped slash kept", "http://x.com/a%2Fb", false, "http://x.com/a%2Fb"},
+		{"escaped slash with trailing slash", "http://x.com/a%2Fb/", false, "http://x.com/a%2Fb"},
+		{"escaped space", "http://x.com/a%20b/", false, "http://x.com/a%20b"},
+		{"case of scheme and host", "HTTP://Example.COM/Path", false, "http://example.com/Path"},
+		{"default port", "https://x.com:443/a", false, "https://x.com/a"},
+		{"other port", "https://x.com:8443/a", false, "https://x.com:8443/a"},
+		{"trailing dot of host", "http://x.com./a", false, "http://x.com/a"},
+		{"unicode host", "http://пример.рф/", false, "http://xn--e1afmkfd.xn--p1ai/"},
+		{"IPv6 host", "http://[::1]:80/", false, "http://[::1]/"},
+		{"sorted query", "http://x.com/?b=2&a=1", false, "http://x.com/?a=1&b=2"},
+		{"empty query", "http://x.com/a?", false, "http://x.com/a"},
+		{"tracking kept", "http://x.com/?utm_source=x&a=1", false, "http://x.com/?a=1&utm_source=x"},
+		{"tracking stripped", "http://x.com/?utm_source=x&GCLID=1&a=1", true, "http://x.com/?a=1"},
+		{"fragment kept", "http://x.com/a/#top", false, "http://x.com/a#top"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Canonical(tt.raw, tt.strip)
+			if err != nil {
+				t.Fatalf("Canonical(%q) error: %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Canonical(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalDistinct(t *testing.T) {
+
+	a, err := Canonical("http://x.com/a%2Fb", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Canonical("http://x.com/a/b", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Fatalf("escaped and plain slash have the same canonical form %q", a)
+	}
+}
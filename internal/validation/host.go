@@ -0,0 +1,94 @@
+package validation
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// asciiHost bring host to lower case punycode form, IP literals are kept as is
+func asciiHost(host string) (string, error) {
+	host = strings.ToLower(strings.Trim(host, "[]."))
+	if host == "" || net.ParseIP(host) != nil {
+		return host, nil
+	}
+	return idna.Lookup.ToASCII(host)
+}
+
+// endsInNumber check if host must be parsed as IPv4 address by browsers,
+// that is its last label is decimal or 0x hex number as in WHATWG URL spec
+func endsInNumber(host string) bool {
+
+	parts := strings.Split(host, ".")
+	last := parts[len(parts)-1]
+	if last == "" {
+		return false
+	}
+	if strings.Trim(last, "0123456789") == "" {
+		return true
+	}
+	if strings.HasPrefix(last, "0x") || strings.HasPrefix(last, "0X") {
+		return strings.Trim(last[2:], "0123456789abcdefABCDEF") == ""
+	}
+	return false
+}
+
+// parseIPv4 parse IPv4 host the way browsers do: 1 to 4 decimal, octal or hex parts,
+// last part fills the remaining bytes, so 2130706433, 0x7f.1 and 127.1 are 127.0.0.1
+func parseIPv4(host string) (net.IP, bool) {
+
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return nil, false
+	}
+
+	nums := make([]uint64, len(parts))
+	for i, part := range parts {
+		n, ok := parseIPv4Number(part)
+		if !ok {
+			return nil, false
+		}
+		if i < len(parts)-1 && n > 255 {
+			return nil, false
+		}
+		nums[i] = n
+	}
+	last := nums[len(nums)-1]
+	if last >= 1<<(8*(5-len(nums))) {
+		return nil, false
+	}
+
+	ip := make(net.IP, net.IPv4len)
+	for i, n := range nums[:len(nums)-1] {
+		ip[i] = byte(n)
+	}
+	for i := net.IPv4len - 1; i >= len(nums)-1; i-- {
+		ip[i] = byte(last)
+		last >>= 8
+	}
+	return ip, true
+}
+
+func parseIPv4Number(s string) (uint64, bool) {
+
+	if s == "" {
+		return 0, false
+	}
+	base := 10
+	switch {
+	case strings.HasPrefix(s, "0x"), strings.HasPrefix(s, "0X"):
+		s, base = s[2:], 16
+		if s == "" {
+			return 0, true
+		}
+	case len(s) > 1 && s[0] == '0':
+		s, base = s[1:], 8
+	}
+	n, err := strconv.ParseUint(s, base, 32)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,271 @@
+package validation
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+)
+
+// Reasons of rejected URL
+const (
+	ReasonInvalidURL  = "invalid_url"
+	ReasonScheme      = "scheme_not_allowed"
+	ReasonPrivateHost = "private_host"
+	ReasonBlocked     = "blocked_domain"
+	ReasonSelfLink    = "self_link"
+)
+
+// Error structure for rejected destination URL
+type Error struct {
+	Reason  string `json:"reason"`
+	Message string `json:"error"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Validator check destination URLs before they are shortened
+type Validator struct {
+	schemes       map[string]bool
+	blockPrivate  bool
+	resolve       bool
+	selfHosts     map[string]bool
+	blocklistFile string
+
+	mu        sync.RWMutex
+	blocklist map[string]bool
+}
+
+func NewValidator(cfg config.Config) (*Validator, error) {
+
+	v := &Validator{
+		schemes:       make(map[string]bool),
+		blockPrivate:  cfg.BlockPrivateHosts,
+		resolve:       cfg.ResolveHosts,
+		selfHosts:     make(map[string]bool),
+		blocklistFile: cfg.BlocklistFile,
+		blocklist:     make(map[string]bool),
+	}
+	for _, val := range cfg.AllowedSchemes {
+		v.schemes[strings.ToLower(strings.TrimSpace(val))] = true
+	}
+	for _, val := range cfg.SelfHosts {
+		v.selfHosts[hostname(val)] = true
+	}
+	if u, err := url.Parse(cfg.BaseURL); err == nil && u.Host != "" {
+		v.selfHosts[hostname(u.Host)] = true
+	}
+
+	if err := v.Reload(); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// Reload read domain blocklist file again, one domain per line, # starts a comment
+func (v *Validator) Reload() error {
+
+	if v.blocklistFile == "" {
+		return nil
+	}
+
+	f, err := os.Open(v.blocklistFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	list := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = hostname(strings.TrimSpace(line))
+		if line != "" {
+			list[line] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	v.blocklist = list
+	v.mu.Unlock()
+
+	return nil
+}
+
+// Check validate destination URL
+func (v *Validator) Check(ctx context.Context, raw string) (*url.URL, error) {
+
+	u, err := url.ParseRequestURI(raw)
+	if err != nil {
+		return nil, &Error{Reason: ReasonInvalidURL, Message: "invalid url"}
+	}
+
+	if !v.schemes[strings.ToLower(u.Scheme)] {
+		return nil, &Error{Reason: ReasonScheme, Message: "scheme " + u.Scheme + " is not allowed"}
+	}
+
+	host, err := asciiHost(u.Hostname())
+	if err != nil {
+		return nil, &Error{Reason: ReasonInvalidURL, Message: "invalid host"}
+	}
+	if host == "" {
+		return nil, &Error{Reason: ReasonInvalidURL, Message: "url has no host"}
+	}
+
+	// numeric hosts are IPv4 addresses for browsers, hosts that look numeric but don't parse are rejected
+	ip := net.ParseIP(host)
+	if ip == nil && endsInNumber(host) {
+		var ok bool
+		if ip, ok = parseIPv4(host); !ok {
+			return nil, &Error{Reason: ReasonInvalidURL, Message: "invalid IPv4 host"}
+		}
+	}
+
+	if v.selfHosts[host] {
+		return nil, &Error{Reason: ReasonSelfLink, Message: "url points to this shortener"}
+	}
+
+	if v.Blocked(host) {
+		return nil, &Error{Reason: ReasonBlocked, Message: "domain " + host + " is blocked"}
+	}
+
+	if v.blockPrivate {
+		private, err := v.private(ctx, host, ip)
+		if err != nil {
+			return nil, &Error{Reason: ReasonInvalidURL, Message: "host can not be resolved"}
+		}
+		if private {
+			return nil, &Error{Reason: ReasonPrivateHost, Message: "private and loopback hosts are not allowed"}
+		}
+	}
+
+	return u, nil
+}
+
+// Blocked check host and its parent domains in blocklist
+func (v *Validator) Blocked(host string) bool {
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	for host != "" {
+		if v.blocklist[host] {
+			return true
+		}
+		i := strings.Index(host, ".")
+		if i < 0 {
+			break
+		}
+		host = host[i+1:]
+	}
+	return false
+}
+
+// private check if host is loopback, private or link local, ip is parsed host if it is IP address,
+// names are resolved if resolving is enabled
+func (v *Validator) private(ctx context.Context, host string, ip net.IP) (bool, error) {
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true, nil
+	}
+
+	if ip != nil {
+		return privateIP(ip), nil
+	}
+
+	if !v.resolve {
+		return false, nil
+	}
+
+	cache, _ := ctx.Value(resolveCacheKey{}).(*resolveCache)
+	if cache != nil {
+		if res, ok := cache.get(host); ok {
+			return res.private, res.err
+		}
+	}
+
+	private, err := resolvePrivate(ctx, host)
+	if cache != nil && ctx.Err() == nil {
+		cache.set(host, resolved{private: private, err: err})
+	}
+	return private, err
+}
+
+func resolvePrivate(ctx context.Context, host string) (bool, error) {
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return false, err
+	}
+	for _, val := range addrs {
+		if privateIP(val.IP) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type resolveCacheKey struct{}
+
+// resolveCache results of host name resolving kept for the life of context
+type resolveCache struct {
+	mu    sync.Mutex
+	hosts map[string]resolved
+}
+
+type resolved struct {
+	private bool
+	err     error
+}
+
+func (c *resolveCache) get(host string) (resolved, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res, ok := c.hosts[host]
+	return res, ok
+}
+
+func (c *resolveCache) set(host string, res resolved) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hosts[host] = res
+}
+
+// WithResolveCache return context in which Check resolves every host name once,
+// used when many URLs are checked at once like in bulk import
+func WithResolveCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, resolveCacheKey{}, &resolveCache{hosts: make(map[string]resolved)})
+}
+
+func privateIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 0 {
+		return true
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// hostname strip port from host and bring it to lower case punycode form
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if h, err := asciiHost(host); err == nil {
+		return h
+	}
+	return strings.ToLower(strings.Trim(host, "[]."))
+}
@@ -0,0 +1,131 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RomanIkonnikov93/URLshortner/cmd/config"
+)
+
+func newTestValidator(t *testing.T) *Validator {
+
+	blocklist := filepath.Join(t.TempDir(), "blocklist.txt")
+	data := "# test list\nxn--e1afmkfd.xn--p1ai\nbad.example\nпример.рф.test # unicode entry\n"
+	if err := os.WriteFile(blocklist, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := NewValidator(config.Config{
+		AllowedSchemes:    []string{"http", "https"},
+		BlockPrivateHosts: true,
+		BlocklistFile:     blocklist,
+		BaseURL:           "http://req.example:80/",
+		SelfHosts:         []string{"short.example:8080", "ссылка.рф"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestCheck(t *testing.T) {
+
+	v := newTestValidator(t)
+
+	tests := []struct {
+		name   string
+		url    string
+		reason string
+	}{
+		{"public host", "https://example.com/path", ""},
+		{"public IP", "http://8.8.8.8/", ""},
+		{"public decimal IP", "http://134744072/", ""},
+		{"not a number", "http://1.2.3.example/", ""},
+		{"relative", "example.com", ReasonInvalidURL},
+		{"scheme", "ftp://example.com/", ReasonScheme},
+		{"javascript", "javascript:alert(1)", ReasonScheme},
+
+		{"loopback", "http://127.0.0.1/", ReasonPrivateHost},
+		{"loopback decimal", "http://2130706433/", ReasonPrivateHost},
+		{"loopback hex", "http://0x7f.1/", ReasonPrivateHost},
+		{"loopback short", "http://127.1/", ReasonPrivateHost},
+		{"loopback octal", "http://0177.0.0.1/", ReasonPrivateHost},
+		{"loopback trailing dot", "http://127.0.0.1./", ReasonPrivateHost},
+		{"private hex", "http://0xa000001/", ReasonPrivateHost},
+		{"private", "http://192.168.1.1:8080/", ReasonPrivateHost},
+		{"zero network", "http://0/", ReasonPrivateHost},
+		{"link local", "http://169.254.169.254/latest/meta-data", ReasonPrivateHost},
+		{"IPv6 loopback", "http://[::1]/", ReasonPrivateHost},
+		{"IPv4 mapped IPv6", "http://[::ffff:127.0.0.1]/", ReasonPrivateHost},
+		{"localhost", "http://localhost:3000/", ReasonPrivateHost},
+		{"localhost subdomain", "http://app.LOCALHOST/", ReasonPrivateHost},
+		{"numeric out of range", "http://4294967296/", ReasonInvalidURL},
+		{"numeric too many parts", "http://1.2.3.4.5/", ReasonInvalidURL},
+		{"bad hex", "http://0x1g.0x2/", ReasonInvalidURL},
+		{"bad octal", "http://1.2.3.09/", ReasonInvalidURL},
+
+		{"blocked", "https://bad.example/", ReasonBlocked},
+		{"blocked subdomain", "https://www.Bad.Example/", ReasonBlocked},
+		{"blocked unicode of punycode entry", "https://пример.рф/", ReasonBlocked},
+		{"blocked punycode of unicode entry", "https://xn--e1afmkfd.xn--p1ai.test/", ReasonBlocked},
+
+		{"self base URL host", "http://req.example/abc", ReasonSelfLink},
+		{"self host", "http://short.example/abc", ReasonSelfLink},
+		{"self punycode of unicode host", "http://xn--80atcxa4d.xn--p1ai/abc", ReasonSelfLink},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Check(context.Background(), tt.url)
+			if tt.reason == "" {
+				if err != nil {
+					t.Fatalf("Check(%q) = %v, want nil", tt.url, err)
+				}
+				return
+			}
+			var verr *Error
+			if !errors.As(err, &verr) {
+				t.Fatalf("Check(%q) = %v, want reason %s", tt.url, err, tt.reason)
+			}
+			if verr.Reason != tt.reason {
+				t.Fatalf("Check(%q) reason = %s, want %s", tt.url, verr.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestParseIPv4(t *testing.T) {
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"127.0.0.1", "127.0.0.1"},
+		{"2130706433", "127.0.0.1"},
+		{"0x7f.1", "127.0.0.1"},
+		{"127.1", "127.0.0.1"},
+		{"10.1.2", "10.1.0.2"},
+		{"0x7F000001", "127.0.0.1"},
+		{"017700000001", "127.0.0.1"},
+		{"0x", "0.0.0.0"},
+		{"256.1.1.1", ""},
+		{"1.2.3.256", ""},
+		{"1.2.65536", ""},
+		{"08.1.1.1", ""},
+		{"1..1", ""},
+	}
+
+	for _, tt := range tests {
+		ip, ok := parseIPv4(tt.host)
+		got := ""
+		if ok {
+			got = ip.String()
+		}
+		if got != tt.want {
+			t.Errorf("parseIPv4(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}